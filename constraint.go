@@ -0,0 +1,273 @@
+package goblin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	leftConstraintDelimiter  string = "<"
+	rightConstraintDelimiter string = ">"
+)
+
+// constraints are constraints of parameters keyed by the name.
+// A constraint reports whether a value of a parameter satisfies it.
+type constraints map[string]func(string) bool
+
+// builtinConstraints are the constraints which can be used without registration.
+var builtinConstraints = constraints{
+	"int":   isInt,
+	"uint":  isUint,
+	"uuid":  isUUID,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"slug":  isSlug,
+	"date":  isDate,
+}
+
+// lookup returns the constraint named name from cs or the built-in constraints.
+func (cs constraints) lookup(name string) (func(string) bool, bool) {
+	if fn, ok := cs[name]; ok {
+		return fn, true
+	}
+	fn, ok := builtinConstraints[name]
+	return fn, ok
+}
+
+// Constraint registers a constraint named name, which can be used in parameters like the built-in constraints. ex. :id<even>
+// fn reports whether a value of a parameter satisfies the constraint.
+// A constraint must be registered before the routes which use it, and a host router has its own constraints.
+func (r *Router) Constraint(name string, fn func(value string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if name == "" || strings.ContainsAny(name, "<>[]/") || fn == nil {
+		r.reportErr(fmt.Errorf("goblin: constraint %q: %w: a constraint must have a valid name and a function", name, ErrInvalidPattern))
+		return
+	}
+	if _, ok := builtinConstraints[name]; ok {
+		r.reportErr(fmt.Errorf("goblin: constraint %s: %w: %s is a built-in constraint", name, ErrInvalidPattern, name))
+		return
+	}
+	r.updateTable(func(t *routeTable) {
+		cs := make(constraints, len(t.constraints)+1)
+		for n, f := range t.constraints {
+			cs[n] = f
+		}
+		cs[name] = fn
+		t.constraints = cs
+	})
+}
+
+// compileParams compiles the patterns and looks up the constraints of the parameters in path in order.
+// A matcher is nil for a parameter without a pattern nor a constraint, and for a catch-all parameter.
+// It returns ErrInvalidPattern if a pattern doesn't compile or a constraint isn't registered.
+// substring is passed to compileParam.
+func compileParams(path string, cs constraints, substring bool) ([]func(string) bool, error) {
+	var matchers []func(string) bool
+	for _, seg := range strings.Split(path, "/") {
+		switch getNodeKind(seg) {
+		case nodeKindStatic:
+			continue
+		case nodeKindRegexp:
+			m, err := compileParam(seg, cs, substring)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, m)
+		default:
+			matchers = append(matchers, nil)
+		}
+	}
+	return matchers, nil
+}
+
+// compileParam returns the matcher of a parameter label which has a pattern or a constraint.
+// A pattern matches a whole segment, unless it starts with ~ or substring is true.
+// ex.
+// :id[\d+]   → MatchString of ^(?:\d+)$
+// :id[~\d+]  → MatchString of \d+
+// :id<int>   → isInt
+func compileParam(label string, cs constraints, substring bool) (func(string) bool, error) {
+	if name := getConstraintName(label); name != "" {
+		fn, ok := cs.lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has an unknown constraint %s", ErrInvalidPattern, label, name)
+		}
+		return fn, nil
+	}
+	ptn := getPattern(label)
+	if strings.HasPrefix(ptn, substringPtnMark) {
+		ptn = ptn[len(substringPtnMark):]
+	} else if !substring {
+		ptn = "^(?:" + ptn + ")$"
+	}
+	reg, err := regexp.Compile(ptn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPattern, label, err)
+	}
+	return reg.MatchString, nil
+}
+
+// getConstraintName gets a constraint name from a label.
+// ex.
+// :id<int>   → int
+// :id[^\d+$] → ""
+// :id        → ""
+func getConstraintName(label string) string {
+	i := strings.IndexAny(label, leftPtnDelimiter+leftConstraintDelimiter)
+	if i == -1 || label[i:i+1] != leftConstraintDelimiter || !strings.HasSuffix(label, rightConstraintDelimiter) {
+		return ""
+	}
+	return label[i+1 : len(label)-1]
+}
+
+// getParamConstraint gets the pattern or the constraint of a parameter label.
+// ex.
+// :id[^\d+$] → ^\d+$
+// :id<int>   → <int>
+// :id        → ""
+func getParamConstraint(label string) string {
+	if name := getConstraintName(label); name != "" {
+		return leftConstraintDelimiter + name + rightConstraintDelimiter
+	}
+	return getPattern(label)
+}
+
+// isInt reports whether s is digits with an optional minus sign. ex. -42
+func isInt(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+// isUint reports whether s is digits. ex. 42
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID reports whether s is a UUID in the canonical form. ex. 123e4567-e89b-12d3-a456-426614174000
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAlpha reports whether s is ASCII letters. ex. abc
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnum reports whether s is ASCII letters and digits. ex. abc123
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSlug reports whether s is lowercase ASCII letters and digits joined by single hyphens. ex. hello-world-2
+func isSlug(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', isDigit(c):
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isDate reports whether s is a valid date in the form of YYYY-MM-DD. ex. 2024-02-29
+func isDate(s string) bool {
+	if len(s) != 10 || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+	if !isUint(s[:4]) || !isUint(s[5:7]) || !isUint(s[8:]) {
+		return false
+	}
+	year := atoi(s[:4])
+	month := atoi(s[5:7])
+	day := atoi(s[8:])
+	return 1 <= month && month <= 12 && 1 <= day && day <= daysIn(year, month)
+}
+
+// daysIn returns the number of days in the month of the year.
+func daysIn(year, month int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
+
+// atoi converts digits s to an int. s must be short digits.
+func atoi(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isHex reports whether c is an ASCII hexadecimal digit.
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
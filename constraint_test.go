@@ -0,0 +1,224 @@
+package goblin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinConstraints(t *testing.T) {
+	cases := []struct {
+		constraint string
+		value      string
+		expected   bool
+	}{
+		{constraint: "int", value: "42", expected: true},
+		{constraint: "int", value: "-42", expected: true},
+		{constraint: "int", value: "-", expected: false},
+		{constraint: "int", value: "4a", expected: false},
+		{constraint: "int", value: "", expected: false},
+		{constraint: "uint", value: "0", expected: true},
+		{constraint: "uint", value: "-1", expected: false},
+		{constraint: "uuid", value: "123e4567-e89b-12d3-a456-426614174000", expected: true},
+		{constraint: "uuid", value: "123E4567-E89B-12D3-A456-426614174000", expected: true},
+		{constraint: "uuid", value: "123e4567e89b12d3a456426614174000", expected: false},
+		{constraint: "uuid", value: "123e4567-e89b-12d3-a456-42661417400g", expected: false},
+		{constraint: "alpha", value: "abcXYZ", expected: true},
+		{constraint: "alpha", value: "abc1", expected: false},
+		{constraint: "alpha", value: "", expected: false},
+		{constraint: "alnum", value: "abc123", expected: true},
+		{constraint: "alnum", value: "abc-123", expected: false},
+		{constraint: "slug", value: "hello-world-2", expected: true},
+		{constraint: "slug", value: "hello", expected: true},
+		{constraint: "slug", value: "Hello-world", expected: false},
+		{constraint: "slug", value: "-hello", expected: false},
+		{constraint: "slug", value: "hello-", expected: false},
+		{constraint: "slug", value: "hello--world", expected: false},
+		{constraint: "date", value: "2024-02-29", expected: true},
+		{constraint: "date", value: "2000-02-29", expected: true},
+		{constraint: "date", value: "2023-02-29", expected: false},
+		{constraint: "date", value: "1900-02-29", expected: false},
+		{constraint: "date", value: "2024-04-31", expected: false},
+		{constraint: "date", value: "2024-13-01", expected: false},
+		{constraint: "date", value: "2024-00-01", expected: false},
+		{constraint: "date", value: "2024-1-01", expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.constraint+"_"+c.value, func(t *testing.T) {
+			fn, ok := constraints(nil).lookup(c.constraint)
+			if !ok {
+				t.Fatalf("actual: %v expected: %v\n", ok, true)
+			}
+			if actual := fn(c.value); actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestCompileParams(t *testing.T) {
+	cases := []struct {
+		name        string
+		path        string
+		values      []string
+		expected    []bool
+		expectedErr error
+	}{
+		{
+			name:     "static",
+			path:     "/foo/bar",
+			values:   nil,
+			expected: nil,
+		},
+		{
+			name:     "param, regexp, constraint and catch-all",
+			path:     `/:id/:num[^\d+$]/:uid<uint>/*path`,
+			values:   []string{"a", "42", "-1", "a/b"},
+			expected: []bool{true, true, false, true},
+		},
+		{
+			name:     "substring pattern",
+			path:     `/:a[\d+]/:b[~\d+]`,
+			values:   []string{"a1", "a1"},
+			expected: []bool{false, true},
+		},
+		{
+			name:        "regexp compile error",
+			path:        `/:id[^(\d+$]`,
+			expectedErr: ErrInvalidPattern,
+		},
+		{
+			name:        "unknown constraint",
+			path:        "/:id<even>",
+			expectedErr: ErrInvalidPattern,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matchers, err := compileParams(c.path, builtinConstraints, false)
+			if !errors.Is(err, c.expectedErr) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.expectedErr)
+			}
+			if len(matchers) != len(c.expected) {
+				t.Fatalf("actual: %v expected: %v\n", len(matchers), len(c.expected))
+			}
+			for i, m := range matchers {
+				actual := m == nil || m(c.values[i])
+				if actual != c.expected[i] {
+					t.Errorf("actual: %v expected: %v\n", actual, c.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConstraintRouting(t *testing.T) {
+	nameHandler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", name, GetParam(r.Context(), "id"))
+		}
+	}
+
+	r := NewRouter()
+	r.Constraint("even", func(v string) bool {
+		return isUint(v) && (v[len(v)-1]-'0')%2 == 0
+	})
+	r.Methods(http.MethodGet).Handler(`/users/:id<even>`, nameHandler("even"))
+	r.Methods(http.MethodGet).Handler(`/users/:id<int>`, nameHandler("int"))
+	r.Methods(http.MethodGet).Handler(`/users/:id<uuid>`, nameHandler("uuid"))
+	r.Methods(http.MethodGet).Handler(`/users/:id[^[a-z]+$]`, nameHandler("regexp"))
+	r.Methods(http.MethodGet).Handler(`/users/:id`, nameHandler("param"))
+	r.Methods(http.MethodGet).Handler(`/users/me`, nameHandler("static"))
+	if err := r.Validate(); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/users/me", expected: "static "},
+		{path: "/users/42", expected: "even 42"},
+		{path: "/users/41", expected: "int 41"},
+		{path: "/users/-42", expected: "int -42"},
+		{path: "/users/123e4567-e89b-12d3-a456-426614174000", expected: "uuid 123e4567-e89b-12d3-a456-426614174000"},
+		{path: "/users/john", expected: "regexp john"},
+		{path: "/users/John-1", expected: "param John-1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestConstraintFailure(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name     string
+		register func(r *Router)
+		expected string
+	}{
+		{
+			name: "unknown constraint",
+			register: func(r *Router) {
+				r.Methods(http.MethodGet).Handler(`/users/:id<even>`, fooHandler)
+			},
+			expected: "unknown constraint even",
+		},
+		{
+			name: "registered after the route",
+			register: func(r *Router) {
+				r.Methods(http.MethodGet).Handler(`/users/:id<even>`, fooHandler)
+				r.Constraint("even", isUint)
+			},
+			expected: "unknown constraint even",
+		},
+		{
+			name: "built-in constraint",
+			register: func(r *Router) {
+				r.Constraint("int", isUint)
+			},
+			expected: "int is a built-in constraint",
+		},
+		{
+			name: "invalid name",
+			register: func(r *Router) {
+				r.Constraint("a<b", isUint)
+			},
+			expected: "a valid name",
+		},
+		{
+			name: "nil function",
+			register: func(r *Router) {
+				r.Constraint("even", nil)
+			},
+			expected: "a function",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewRouter()
+			c.register(r)
+			err := r.Validate()
+			if !errors.Is(err, ErrInvalidPattern) || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("actual: %v expected: %v\n", err, c.expected)
+			}
+		})
+	}
+}
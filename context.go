@@ -8,20 +8,52 @@ import (
 type paramsKey struct{}
 
 // ParamsKey is the request context key under which URL params are stored.
+// A router stores them as *Params, and ParamsFromContext reads both *Params and Params.
 var ParamsKey = paramsKey{}
 
-// GetParam gets parameters from request.
-func GetParam(ctx context.Context, name string) string {
-	params, ok := ctx.Value(ParamsKey).(Params)
-	if !ok {
-		return ""
-	}
+// paramsContext is a context which has parameters under ParamsKey.
+// Unlike a context of context.WithValue, it holds the parameters without boxing them, which saves an allocation.
+type paramsContext struct {
+	context.Context
+	ps Params
+}
 
-	for i := 0; i < len(params); i++ {
-		if params[i].key == name {
-			return params[i].value
-		}
+// withParams returns a copy of ctx which has ps under ParamsKey.
+func withParams(ctx context.Context, ps Params) context.Context {
+	return &paramsContext{Context: ctx, ps: ps}
+}
+
+// Value returns a pointer to the parameters for ParamsKey, otherwise the value of the parent context.
+func (c *paramsContext) Value(key any) any {
+	if _, ok := key.(paramsKey); ok {
+		return &c.ps
 	}
+	return c.Context.Value(key)
+}
 
-	return ""
+// matchedRouteKey represents the key for a matched route
+type matchedRouteKey struct{}
+
+// MatchedRoute represents the route which matches a request.
+type MatchedRoute struct {
+	// Method is the method of the route. It is GET for a HEAD request handled by the GET route with ImplicitHEAD.
+	Method string
+	// Pattern is the cleaned path of the route as registered. ex. /users/:id, /items/{id}
+	// It is the prefix for a handler mounted with Mount. ex. /admin
+	Pattern string
+	// Name is the name of the route. It is empty if the route doesn't have a name.
+	Name string
+}
+
+// RouteFromContext gets the matched route from a context of a request.
+// It returns nil if the router doesn't save the matched route. See Router.SaveMatchedRoute.
+func RouteFromContext(ctx context.Context) *MatchedRoute {
+	mr, _ := ctx.Value(matchedRouteKey{}).(*MatchedRoute)
+	return mr
+}
+
+// GetParam gets parameters from request.
+func GetParam(ctx context.Context, name string) string {
+	v, _ := lookupParam(ctx, name)
+	return v
 }
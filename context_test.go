@@ -2,6 +2,10 @@ package goblin
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -55,3 +59,148 @@ func TestGetParam(t *testing.T) {
 		})
 	}
 }
+
+func TestRouteFromContext(t *testing.T) {
+	routeMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mr := RouteFromContext(r.Context()); mr != nil {
+				fmt.Fprintf(w, "middleware: %s %s %s\n", mr.Method, mr.Pattern, mr.Name)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	newRouter := func(save bool) *Router {
+		r := NewRouter()
+		r.SaveMatchedRoute = save
+		r.UseGlobal(routeMiddleware)
+		r.Methods(http.MethodGet).Name("user").Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mr := RouteFromContext(r.Context()); mr != nil {
+				fmt.Fprintf(w, "handler: %s %s %s\n", mr.Method, mr.Pattern, mr.Name)
+			}
+		}))
+		r.Methods(http.MethodGet).Handler(`/items/{id:[0-9]+}`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mr := RouteFromContext(r.Context()); mr != nil {
+				fmt.Fprintf(w, "handler: %s %s %s\n", mr.Method, mr.Pattern, mr.Name)
+			}
+		}))
+		r.Mount(`/shops/{shop}`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mr := RouteFromContext(r.Context()); mr != nil {
+				fmt.Fprintf(w, "mounted: %s %s %s\n", mr.Method, mr.Pattern, r.URL.Path)
+			}
+		}))
+		r.Mount(`/admin/:tenant`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mr := RouteFromContext(r.Context()); mr != nil {
+				fmt.Fprintf(w, "mounted: %s %s %s\n", mr.Method, mr.Pattern, r.URL.Path)
+			}
+		}))
+		return r
+	}
+
+	cases := []struct {
+		router   *Router
+		method   string
+		path     string
+		expected string
+	}{
+		{
+			router:   newRouter(true),
+			method:   http.MethodGet,
+			path:     "/users/1",
+			expected: "middleware: GET /users/:id user\nhandler: GET /users/:id user\n",
+		},
+		{
+			router:   newRouter(true),
+			method:   http.MethodGet,
+			path:     "/admin/acme/users/1",
+			expected: "middleware: GET /admin/:tenant \nmounted: GET /admin/:tenant /users/1\n",
+		},
+		{
+			router:   newRouter(true),
+			method:   http.MethodGet,
+			path:     "/items/1",
+			expected: "middleware: GET /items/{id:[0-9]+} \nhandler: GET /items/{id:[0-9]+} \n",
+		},
+		{
+			router:   newRouter(true),
+			method:   http.MethodGet,
+			path:     "/shops/acme/items",
+			expected: "middleware: GET /shops/{shop} \nmounted: GET /shops/{shop} /items\n",
+		},
+		{
+			router:   newRouter(true),
+			method:   http.MethodPost,
+			path:     "/admin/acme",
+			expected: "middleware: POST /admin/:tenant \nmounted: POST /admin/:tenant /\n",
+		},
+		{
+			router:   newRouter(false),
+			method:   http.MethodGet,
+			path:     "/users/1",
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%s_%t", c.method, c.path, c.router.SaveMatchedRoute), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			c.router.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+		})
+	}
+
+	// A HEAD request handled by the GET route has the GET route.
+	var actual *MatchedRoute
+	r := NewRouter()
+	r.SaveMatchedRoute = true
+	r.ImplicitHEAD = true
+	r.Methods(http.MethodGet).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = RouteFromContext(r.Context())
+	}))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/foo", nil))
+
+	expected := &MatchedRoute{Method: http.MethodGet, Pattern: "/foo"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual: %v expected: %v\n", actual, expected)
+	}
+}
+
+func TestParamsContext(t *testing.T) {
+	type otherKey struct{}
+	parent := context.WithValue(context.Background(), otherKey{}, "other")
+	ctx := withParams(parent, NewParams("id", "42"))
+
+	if ps, ok := ctx.Value(ParamsKey).(*Params); !ok || ps.Get("id") != "42" {
+		t.Errorf("actual: %v expected: %v\n", ctx.Value(ParamsKey), NewParams("id", "42"))
+	}
+	if v := ctx.Value(otherKey{}); v != "other" {
+		t.Errorf("actual: %v expected: %v\n", v, "other")
+	}
+
+	// Parameters stored with context.WithValue can be read as well.
+	ctx = context.WithValue(ctx, ParamsKey, NewParams("id", "43"))
+	if v := GetParam(ctx, "id"); v != "43" {
+		t.Errorf("actual: %v expected: %v\n", v, "43")
+	}
+}
+
+func TestParamsContextAllocs(t *testing.T) {
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/users/:id/posts/:slug`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = GetParam(r.Context(), "id")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/users/42/posts/hello", nil)
+	rec := httptest.NewRecorder()
+
+	// A copy of the parameters, the context and the request.
+	allocs := testing.AllocsPerRun(100, func() {
+		r.ServeHTTP(rec, req)
+	})
+	if allocs != 3 {
+		t.Errorf("actual: %v expected: %v\n", allocs, 3)
+	}
+}
@@ -0,0 +1,183 @@
+package goblin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group represents a group of routes which share a path prefix, middlewares and error handlers.
+// Routes of a group are registered to the router as routes which have the full path and all middlewares.
+type Group struct {
+	router      *Router
+	parent      *Group
+	prefix      string
+	written     string              // prefix as registered. ex. /tenants/{tenant}
+	matchers    []func(string) bool // compiled patterns and constraints of the parameters of prefix
+	middlewares middlewares
+	// NotFoundHandler overrides NotFoundHandler of the router for paths under the prefix.
+	NotFoundHandler http.Handler
+	// MethodNotAllowedHandler overrides MethodNotAllowedHandler of the router for paths under the prefix.
+	MethodNotAllowedHandler http.Handler
+}
+
+// Group creates a new group of routes under prefix, and calls fn with it if fn is not nil.
+func (r *Router) Group(prefix string, fn func(g *Group)) *Group {
+	return r.newGroup(nil, prefix, fn)
+}
+
+// Group creates a new group of routes nested in g, and calls fn with it if fn is not nil.
+// The prefix is appended to the prefix of g.
+func (g *Group) Group(prefix string, fn func(g *Group)) *Group {
+	return g.router.newGroup(g, prefix, fn)
+}
+
+// newGroup creates a new group and registers it to the router.
+func (r *Router) newGroup(parent *Group, prefix string, fn func(g *Group)) *Group {
+	written := joinPath(parent.writtenPath(""), prefix)
+	// An invalid prefix is kept as it is, and it is reported by the routes of the group.
+	if p, err := parsePattern(prefix); err == nil {
+		prefix = p
+	}
+	g := &Group{
+		router:  r,
+		parent:  parent,
+		prefix:  joinPath(parent.path(""), prefix),
+		written: written,
+	}
+
+	r.mu.Lock()
+	r.updateTable(func(t *routeTable) {
+		g.matchers, _ = compileParams(g.prefix, t.constraints, r.settings().SubstringPatterns)
+		t.groups = append(t.groups, g)
+	})
+	r.mu.Unlock()
+
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+// UseGroup sets middlewares of the group.
+// They are applied to routes registered after the call, after the middlewares of the parent groups.
+func (g *Group) UseGroup(mws ...middleware) {
+	g.middlewares = NewMiddlewares(mws)
+}
+
+// Use creates a new route in the group and sets middlewares.
+func (g *Group) Use(mws ...middleware) *Route {
+	return g.newRoute().Use(mws...)
+}
+
+// Methods creates a new route in the group and sets methods.
+func (g *Group) Methods(methods ...string) *Route {
+	return g.newRoute().Methods(methods...)
+}
+
+// newRoute creates a new route in the group.
+func (g *Group) newRoute() *Route {
+	return &Route{
+		router: g.router,
+		group:  g,
+	}
+}
+
+// path returns the full path of p in the group. ex. /api/v1 and /users → /api/v1/users
+func (g *Group) path(p string) string {
+	if g == nil {
+		return p
+	}
+	return joinPath(g.prefix, p)
+}
+
+// writtenPath returns the full path of p in the group as registered. ex. /tenants/{tenant} and /users → /tenants/{tenant}/users
+func (g *Group) writtenPath(p string) string {
+	if g == nil {
+		return p
+	}
+	return joinPath(g.written, p)
+}
+
+// allMiddlewares returns the middlewares of the group following the middlewares of the parent groups.
+func (g *Group) allMiddlewares() middlewares {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.allMiddlewares(), g.middlewares...)
+}
+
+// joinPath joins prefix and path. A trailing slash of prefix is removed. ex. /api/ and /users → /api/users
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if path == "" {
+		return prefix
+	}
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	return prefix + path
+}
+
+// errorHandlers returns the NotFoundHandler and the MethodNotAllowedHandler for path from the groups of t.
+// The handlers of the group which has the longest prefix matching path take priority over the handlers of the router.
+func (r *Router) errorHandlers(t *routeTable, path string) (notFound http.Handler, methodNotAllowed http.Handler) {
+	notFound, methodNotAllowed = r.NotFoundHandler, r.MethodNotAllowedHandler
+	nfLen, mnaLen := -1, -1
+	for _, g := range t.groups {
+		if g.NotFoundHandler == nil && g.MethodNotAllowedHandler == nil {
+			continue
+		}
+		l, ok := r.matchPrefix(g.prefix, g.matchers, path)
+		if !ok {
+			continue
+		}
+		if g.NotFoundHandler != nil && l >= nfLen {
+			notFound, nfLen = g.NotFoundHandler, l
+		}
+		if g.MethodNotAllowedHandler != nil && l >= mnaLen {
+			methodNotAllowed, mnaLen = g.MethodNotAllowedHandler, l
+		}
+	}
+	return notFound, methodNotAllowed
+}
+
+// matchPrefix reports whether path is under prefix segment by segment, and returns the number of segments of prefix.
+// A parameter segment of prefix matches any segment which satisfies its matcher in matchers,
+// and a catch-all segment matches the rest of path.
+// ex.
+// /users/:id and /users/42/posts → 2, true
+// /users/:id and /users          → 0, false
+// /api       and /apiv2          → 0, false
+func (r *Router) matchPrefix(prefix string, matchers []func(string) bool, path string) (int, bool) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return 0, true
+	}
+	pSegs := strings.Split(prefix, "/")
+	segs := strings.Split(strings.Trim(cleanPath(path), "/"), "/")
+	if len(segs) < len(pSegs) {
+		return 0, false
+	}
+
+	j := 0
+	for i, ps := range pSegs {
+		seg := segs[i]
+		switch getNodeKind(ps) {
+		case nodeKindCatchAll:
+			return len(pSegs), true
+		case nodeKindRegexp, nodeKindParam:
+			if seg == "" {
+				return 0, false
+			}
+			if j < len(matchers) && matchers[j] != nil && !matchers[j](seg) {
+				return 0, false
+			}
+			j++
+		default:
+			if len(seg) != len(ps) || !hasPrefix(seg, ps, r.CaseInsensitive) {
+				return 0, false
+			}
+		}
+	}
+	return len(pSegs), true
+}
@@ -0,0 +1,232 @@
+package goblin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	r := NewRouter()
+
+	r.UseGlobal(global)
+	r.Group(`/api`, func(api *Group) {
+		api.UseGroup(first)
+		api.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/api/\n")
+		}))
+		api.Group(`/v1/`, func(v1 *Group) {
+			v1.UseGroup(second)
+			v1.Methods(http.MethodGet).Use(third).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id := GetParam(r.Context(), "id")
+				fmt.Fprintf(w, "/api/v1/users/%v\n", id)
+			}))
+		})
+		api.Methods(http.MethodGet).Handler(`/health`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/api/health\n")
+		}))
+	})
+	r.Methods(http.MethodGet).Handler(`/health`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/health\n")
+	}))
+
+	cases := []routerTest{
+		{
+			path:   "/api/",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nfirst: before\n/api/\nfirst: after\nglobal: after\n",
+		},
+		{
+			path:   "/api/v1/users/1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nfirst: before\nsecond: before\nthird: before\n/api/v1/users/1\nthird: after\nsecond: after\nfirst: after\nglobal: after\n",
+		},
+		{
+			path:   "/api/health",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nfirst: before\n/api/health\nfirst: after\nglobal: after\n",
+		},
+		{
+			path:   "/health",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\n/health\nglobal: after\n",
+		},
+		{
+			path:   "/users/1",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	errorHandler := func(body string, code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, body)
+		})
+	}
+
+	r := NewRouter()
+	r.NotFoundHandler = errorHandler("router: not found", http.StatusNotFound)
+	r.Group(`/api`, func(api *Group) {
+		api.NotFoundHandler = errorHandler("api: not found", http.StatusNotFound)
+		api.MethodNotAllowedHandler = errorHandler("api: method not allowed", http.StatusMethodNotAllowed)
+		api.Methods(http.MethodGet).Handler(`/foo`, fooHandler)
+		api.Group(`/users/:id[^\d+$]`, func(users *Group) {
+			users.NotFoundHandler = errorHandler("users: not found", http.StatusNotFound)
+			users.Methods(http.MethodGet).Handler(`/posts`, fooHandler)
+		})
+	})
+
+	cases := []routerTest{
+		{
+			path:   "/bar",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "router: not found",
+		},
+		{
+			path:   "/apiv2/bar",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "router: not found",
+		},
+		{
+			path:   "/api/bar",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "api: not found",
+		},
+		{
+			path:   "/api/foo",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			body:   "api: method not allowed",
+		},
+		{
+			path:   "/api/users/1/bar",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "users: not found",
+		},
+		{
+			path:   "/api/users/john/bar",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "api: not found",
+		},
+		{
+			// The handler of the parent group is used if the nested group doesn't override it.
+			path:   "/api/users/1/posts",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			body:   "api: method not allowed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	cases := []struct {
+		prefix   string
+		path     string
+		expected string
+	}{
+		{prefix: "", path: "/foo", expected: "/foo"},
+		{prefix: "/", path: "/foo", expected: "/foo"},
+		{prefix: "/api", path: "/foo", expected: "/api/foo"},
+		{prefix: "/api/", path: "foo", expected: "/api/foo"},
+		{prefix: "/api", path: "/", expected: "/api/"},
+		{prefix: "/api", path: "", expected: "/api"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.prefix+"_"+c.path, func(t *testing.T) {
+			actual := joinPath(c.prefix, c.path)
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestMatchPrefix(t *testing.T) {
+	cases := []struct {
+		prefix   string
+		path     string
+		expected int
+		ok       bool
+	}{
+		{prefix: "", path: "/foo", expected: 0, ok: true},
+		{prefix: "/api", path: "/api", expected: 1, ok: true},
+		{prefix: "/api", path: "/api/foo", expected: 1, ok: true},
+		{prefix: "/api", path: "/apiv2/foo", expected: 0, ok: false},
+		{prefix: "/api", path: "/", expected: 0, ok: false},
+		{prefix: "/users/:id", path: "/users/42/posts", expected: 2, ok: true},
+		{prefix: "/users/:id", path: "/users", expected: 0, ok: false},
+		{prefix: `/users/:id[^\d+$]`, path: "/users/john", expected: 0, ok: false},
+		{prefix: `/users/:id[^\d+$]/posts/:slug<slug>`, path: "/users/42/posts/hello", expected: 4, ok: true},
+		{prefix: `/users/:id[^\d+$]/posts/:slug<slug>`, path: "/users/42/posts/Hello", expected: 0, ok: false},
+		{prefix: "/files/*path", path: "/files/a/b", expected: 2, ok: true},
+	}
+
+	r := NewRouter()
+	for _, c := range cases {
+		t.Run(c.prefix+"_"+c.path, func(t *testing.T) {
+			matchers, err := compileParams(c.prefix, nil, false)
+			if err != nil {
+				t.Fatalf("actual: %v expected: %v\n", err, nil)
+			}
+			actual, ok := r.matchPrefix(c.prefix, matchers, c.path)
+			if actual != c.expected || ok != c.ok {
+				t.Errorf("actual: %v %v expected: %v %v\n", actual, ok, c.expected, c.ok)
+			}
+		})
+	}
+}
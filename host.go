@@ -0,0 +1,145 @@
+package goblin
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	leftHostParamDelimiter  string = "{"
+	rightHostParamDelimiter string = "}"
+)
+
+// hostRouter is a router for requests whose host matches a pattern.
+type hostRouter struct {
+	pattern string
+	labels  []string
+	static  bool // whether pattern doesn't have any host parameters
+	router  *Router
+}
+
+// Host returns the router for requests whose host matches pattern, creating it if necessary.
+// A label enclosed in braces is a host parameter which matches any label. ex. {tenant}.example.com
+// Host parameters can be read with GetParam.
+// A pattern without host parameters takes priority, and the port of a request is ignored.
+// Requests whose host doesn't match any patterns are handled by r.
+// The host router uses the settings of r, such as NotFoundHandler, StrictSlash and StrictRegistration,
+// and its own settings are ignored.
+func (r *Router) Host(pattern string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pattern = strings.TrimSuffix(pattern, ".")
+	for _, hr := range r.loadTable().hosts {
+		if strings.EqualFold(hr.pattern, pattern) {
+			return hr.router
+		}
+	}
+
+	hr := &hostRouter{
+		pattern: pattern,
+		labels:  strings.Split(pattern, "."),
+		static:  true,
+		router:  NewRouter(),
+	}
+	hr.router.parent = r
+	for _, l := range hr.labels {
+		if !isHostParam(l) {
+			continue
+		}
+		hr.static = false
+		if getHostParamName(l) == "" {
+			r.reportErr(fmt.Errorf("goblin: host %s: %w: %s doesn't have a name", pattern, ErrInvalidPattern, l))
+		}
+	}
+	r.updateTable(func(t *routeTable) {
+		t.hosts = append(t.hosts, hr)
+	})
+	return hr.router
+}
+
+// serveHost dispatches the request to the router of the host if the host matches a pattern.
+// It reports whether the request is dispatched.
+func (r *Router) serveHost(w http.ResponseWriter, req *http.Request, t *routeTable) bool {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+
+	hr, params := t.matchHost(labels)
+	if hr == nil {
+		return false
+	}
+
+	// The routes of the host router are served with the settings of r.
+	ht := hr.router.loadTable()
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.serve(w, req, ht)
+	})
+	if t.globalMiddlewares != nil {
+		h = t.globalMiddlewares.then(h)
+	}
+	if params != nil {
+		req = req.WithContext(withParams(req.Context(), params))
+	}
+	h.ServeHTTP(w, req)
+	return true
+}
+
+// matchHost returns the host router which matches labels of a host, and the host parameters.
+func (t *routeTable) matchHost(labels []string) (*hostRouter, Params) {
+	for _, hr := range t.hosts {
+		if hr.static && matchHostLabels(hr.labels, labels, nil) {
+			return hr, nil
+		}
+	}
+	for _, hr := range t.hosts {
+		if hr.static {
+			continue
+		}
+		params := make(Params, 0, len(hr.labels))
+		if matchHostLabels(hr.labels, labels, &params) {
+			return hr, params
+		}
+	}
+	return nil, nil
+}
+
+// matchHostLabels reports whether labels of a host match labels of a pattern.
+// Host parameters are appended to ps.
+func matchHostLabels(pattern []string, labels []string, ps *Params) bool {
+	if len(pattern) != len(labels) {
+		return false
+	}
+	for i, p := range pattern {
+		if isHostParam(p) {
+			if labels[i] == "" {
+				return false
+			}
+			*ps = append(*ps, Param{
+				key:   getHostParamName(p),
+				value: labels[i],
+			})
+			continue
+		}
+		if !strings.EqualFold(p, labels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHostParam reports whether a label of a host pattern is a host parameter. ex. {tenant}
+func isHostParam(label string) bool {
+	return strings.HasPrefix(label, leftHostParamDelimiter) && strings.HasSuffix(label, rightHostParamDelimiter)
+}
+
+// getHostParamName gets a host parameter name from a label.
+// ex.
+// {tenant} → tenant
+func getHostParamName(label string) string {
+	return label[len(leftHostParamDelimiter) : len(label)-len(rightHostParamDelimiter)]
+}
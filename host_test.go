@@ -0,0 +1,328 @@
+package goblin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	r := NewRouter()
+	r.UseGlobal(global)
+	r.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := GetParam(r.Context(), "id")
+		fmt.Fprintf(w, "default: /users/%v\n", id)
+	}))
+
+	api := r.Host("api.example.com")
+	api.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := GetParam(r.Context(), "id")
+		fmt.Fprintf(w, "api: /users/%v\n", id)
+	}))
+
+	tenant := r.Host("{tenant}.example.com")
+	tenant.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := GetParam(r.Context(), "tenant")
+		id := GetParam(r.Context(), "id")
+		fmt.Fprintf(w, "tenant: %v /users/%v\n", tenant, id)
+	}))
+	tenant.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := GetParam(r.Context(), "tenant")
+		fmt.Fprintf(w, "tenant: %v /\n", tenant)
+	}))
+
+	if r.Host("{tenant}.example.com.") != tenant {
+		t.Fatalf("actual: %v expected: %v\n", "a new router", "the same router")
+	}
+
+	cases := []struct {
+		host string
+		routerTest
+	}{
+		{
+			host: "example.com",
+			routerTest: routerTest{
+				path:   "/users/1",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\ndefault: /users/1\nglobal: after\n",
+			},
+		},
+		{
+			host: "api.example.com",
+			routerTest: routerTest{
+				path:   "/users/1",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\napi: /users/1\nglobal: after\n",
+			},
+		},
+		{
+			host: "API.Example.com:8080",
+			routerTest: routerTest{
+				path:   "/users/1",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\napi: /users/1\nglobal: after\n",
+			},
+		},
+		{
+			host: "acme.example.com",
+			routerTest: routerTest{
+				path:   "/users/1",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\ntenant: acme /users/1\nglobal: after\n",
+			},
+		},
+		{
+			host: "Acme.example.com",
+			routerTest: routerTest{
+				path:   "/",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\ntenant: Acme /\nglobal: after\n",
+			},
+		},
+		{
+			host: "foo.acme.example.com",
+			routerTest: routerTest{
+				path:   "/users/1",
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   "global: before\ndefault: /users/1\nglobal: after\n",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.host+"_"+c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			req.Host = c.host
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestHostNotFound(t *testing.T) {
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r.Host("api.example.com").Methods(http.MethodGet).Handler(`/bar`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// A host router doesn't fall back to the routes of r.
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	req.Host = "api.example.com"
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("actual: %v expected: %v\n", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHostSettings(t *testing.T) {
+	idHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "id:%v pathvalue:%v", GetParam(r.Context(), "id"), r.PathValue("id"))
+	})
+	statusHandler := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		})
+	}
+
+	cases := []struct {
+		name     string
+		set      func(r *Router)
+		path     string
+		method   string
+		code     int
+		body     string
+		location string
+	}{
+		{
+			name:   "SetPathValue",
+			set:    func(r *Router) { r.SetPathValue = true },
+			path:   "/users/1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "id:1 pathvalue:1",
+		},
+		{
+			name:   "ImplicitHEAD",
+			set:    func(r *Router) { r.ImplicitHEAD = true },
+			path:   "/users/1",
+			method: http.MethodHead,
+			code:   http.StatusOK,
+		},
+		{
+			name:   "SubstringPatterns",
+			set:    func(r *Router) { r.SubstringPatterns = true },
+			path:   "/items/a1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "id:a1 pathvalue:",
+		},
+		{
+			name:   "NotFoundHandler",
+			set:    func(r *Router) { r.NotFoundHandler = statusHandler(http.StatusTeapot) },
+			path:   "/foo",
+			method: http.MethodGet,
+			code:   http.StatusTeapot,
+		},
+		{
+			name:   "MethodNotAllowedHandler",
+			set:    func(r *Router) { r.MethodNotAllowedHandler = statusHandler(http.StatusTeapot) },
+			path:   "/users/1",
+			method: http.MethodPost,
+			code:   http.StatusTeapot,
+		},
+		{
+			name:   "DefaultOPTIONSHandler",
+			set:    func(r *Router) { r.DefaultOPTIONSHandler = statusHandler(http.StatusTeapot) },
+			path:   "/users/1",
+			method: http.MethodOptions,
+			code:   http.StatusTeapot,
+		},
+		{
+			name:   "StrictSlash",
+			set:    func(r *Router) { r.StrictSlash = true },
+			path:   "/users/1/",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+		{
+			name:     "RedirectTrailingSlash",
+			set:      func(r *Router) { r.RedirectTrailingSlash = true },
+			path:     "/users/1/",
+			method:   http.MethodGet,
+			code:     http.StatusMovedPermanently,
+			location: "/users/1",
+		},
+		{
+			name:   "CaseInsensitive",
+			set:    func(r *Router) { r.CaseInsensitive = true },
+			path:   "/USERS/1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "id:1 pathvalue:",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewRouter()
+			c.set(r)
+			api := r.Host("api.example.com")
+			api.Methods(http.MethodGet).Handler(`/users/:id`, idHandler)
+			api.Methods(http.MethodGet).Handler(`/items/:id[\d+]`, idHandler)
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			req.Host = "api.example.com"
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+			if location := rec.Header().Get("Location"); location != c.location {
+				t.Errorf("actual: %v expected: %v\n", location, c.location)
+			}
+			if c.location == "" && rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+
+	// StrictRegistration of the parent router makes an invalid route of a host router panic.
+	r := NewRouter()
+	r.StrictRegistration = true
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("actual: %v expected: %v\n", err, ErrInvalidPattern)
+		}
+	}()
+	r.Host("api.example.com").Methods(http.MethodGet).Handler(`/x/:id[`, idHandler)
+}
+
+func TestHostValidate(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Host("{}.example.com")
+	r.Host("api.example.com").Methods(http.MethodGet).Handler(`/foo/:id`, fooHandler)
+	r.Host("api.example.com").Methods(http.MethodGet).Handler(`/foo/:name`, fooHandler)
+
+	err := r.Validate()
+	for _, expected := range []error{ErrInvalidPattern, ErrAmbiguousRoute} {
+		if !errors.Is(err, expected) {
+			t.Errorf("actual: %v expected: %v\n", err, expected)
+		}
+	}
+}
+
+func TestMatchHostLabels(t *testing.T) {
+	cases := []struct {
+		pattern        []string
+		labels         []string
+		expected       bool
+		expectedParams Params
+	}{
+		{
+			pattern:        []string{"api", "example", "com"},
+			labels:         []string{"api", "example", "com"},
+			expected:       true,
+			expectedParams: Params{},
+		},
+		{
+			pattern:        []string{"api", "example", "com"},
+			labels:         []string{"example", "com"},
+			expected:       false,
+			expectedParams: Params{},
+		},
+		{
+			pattern:        []string{"{tenant}", "{region}", "example", "com"},
+			labels:         []string{"acme", "eu", "example", "com"},
+			expected:       true,
+			expectedParams: Params{{key: "tenant", value: "acme"}, {key: "region", value: "eu"}},
+		},
+		{
+			pattern:        []string{"{tenant}", "example", "com"},
+			labels:         []string{"", "example", "com"},
+			expected:       false,
+			expectedParams: Params{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v_%v", c.pattern, c.labels), func(t *testing.T) {
+			ps := Params{}
+			actual := matchHostLabels(c.pattern, c.labels, &ps)
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+			if c.expected && !reflect.DeepEqual(ps, c.expectedParams) {
+				t.Errorf("actual: %v expected: %v\n", ps, c.expectedParams)
+			}
+		})
+	}
+}
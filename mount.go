@@ -0,0 +1,96 @@
+package goblin
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParamName is the name of the catch-all parameter which captures the path under a mounted prefix.
+const mountParamName = "goblinMountPath"
+
+// Mount mounts h under prefix.
+// A request for prefix or a path under it is passed to h with prefix stripped from the path. ex. /admin/users → /users
+// A trailing slash of the path is kept, so that h can have its own trailing slash policy. ex. /admin/users/ → /users/
+// h can be another Router, which can read parameters of prefix with GetParam. ex. /tenants/:tenant
+func (r *Router) Mount(prefix string, h http.Handler) {
+	r.mount(nil, prefix, h)
+}
+
+// Mount mounts h under prefix in the group. The middlewares of the group are applied to h.
+func (g *Group) Mount(prefix string, h http.Handler) {
+	g.router.mount(g, prefix, h)
+}
+
+// mount registers h for prefix and paths under it for all methods.
+func (r *Router) mount(g *Group, prefix string, h http.Handler) {
+	path := joinPath(g.path(""), prefix)
+	written := joinPath(g.writtenPath(""), prefix)
+	if path == "" {
+		path, written = "/", "/"
+	}
+	mp := path
+	if p, err := parsePattern(path); err == nil {
+		mp = canonicalPath(p)
+	}
+	if written = canonicalPath(written); written == mp {
+		written = ""
+	}
+	a := action{
+		middlewares: g.allMiddlewares(),
+		handler:     mountHandler(h),
+		written:     written,
+		mount:       mp,
+	}
+	r.handle(allMethods, path, a, false)
+	r.handle(allMethods, joinPath(path, catchAllDelimiter+mountParamName), a, false)
+}
+
+// mountHandler returns a handler which strips a mounted prefix from the path and serves the request with h.
+// Parameters of the prefix are kept in the context of the request.
+func mountHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := ParamsFromContext(req.Context())
+
+		rest := "/"
+		ps := make(Params, 0, len(params))
+		for _, p := range params {
+			if p.key == mountParamName {
+				rest += p.value
+				continue
+			}
+			ps = append(ps, p)
+		}
+		// The parameter doesn't have a trailing slash unless the router has StrictSlash, so that it is taken from
+		// the request path for h. ex. /admin/users/ → /users/
+		if rest != "/" && !strings.HasSuffix(rest, "/") && strings.HasSuffix(cleanPath(req.URL.Path), "/") {
+			rest += "/"
+		}
+
+		r2 := new(http.Request)
+		*r2 = *req
+		r2.URL = new(url.URL)
+		*r2.URL = *req.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = stripRawPath(req.URL.RawPath, rest)
+		if params != nil {
+			r2 = r2.WithContext(withParams(req.Context(), ps))
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// stripRawPath returns the end of rawPath which is the escaped form of rest, as http.StripPrefix strips RawPath.
+// It returns "" if rawPath is empty or doesn't end with rest. ex. /admin/a%2Fb and /a/b → /a%2Fb
+func stripRawPath(rawPath string, rest string) string {
+	for i := strings.LastIndex(rawPath, "/"); i >= 0; i = strings.LastIndex(rawPath[:i], "/") {
+		p, err := url.PathUnescape(rawPath[i:])
+		if err != nil || len(p) > len(rest) {
+			return ""
+		}
+		if p == rest {
+			return rawPath[i:]
+		}
+	}
+	return ""
+}
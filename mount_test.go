@@ -0,0 +1,314 @@
+package goblin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	admin := NewRouter()
+	admin.UseGlobal(second)
+	admin.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin: /\n")
+	}))
+	admin.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := GetParam(r.Context(), "id")
+		fmt.Fprintf(w, "admin: /users/%v\n", id)
+	}))
+
+	tenant := NewRouter()
+	tenant.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := GetParam(r.Context(), "tenant")
+		id := GetParam(r.Context(), "id")
+		fmt.Fprintf(w, "tenant: %v /users/%v\n", tenant, id)
+	}))
+	tenant.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := GetParam(r.Context(), "tenant")
+		path := GetParam(r.Context(), mountParamName)
+		fmt.Fprintf(w, "tenant: %v /%v\n", tenant, path)
+	}))
+
+	static := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "static: %v\n", r.URL.Path)
+	})
+
+	r := NewRouter()
+	r.UseGlobal(global)
+	r.Mount(`/admin`, admin)
+	r.Mount(`/tenants/:tenant/`, tenant)
+	r.Group(`/assets`, func(g *Group) {
+		g.UseGroup(first)
+		g.Mount(`/`, static)
+	})
+
+	cases := []routerTest{
+		{
+			path:   "/admin",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nsecond: before\nadmin: /\nsecond: after\nglobal: after\n",
+		},
+		{
+			path:   "/admin/",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nsecond: before\nadmin: /\nsecond: after\nglobal: after\n",
+		},
+		{
+			path:   "/admin/users/1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nsecond: before\nadmin: /users/1\nsecond: after\nglobal: after\n",
+		},
+		{
+			path:   "/tenants/acme/users/1",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\ntenant: acme /users/1\nglobal: after\n",
+		},
+		{
+			path:   "/tenants/acme",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\ntenant: acme /\nglobal: after\n",
+		},
+		{
+			path:   "/assets/css/main.css",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "global: before\nfirst: before\nstatic: /css/main.css\nfirst: after\nglobal: after\n",
+		},
+		{
+			path:   "/foo",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestMountStrictSlash(t *testing.T) {
+	admin := NewRouter()
+	admin.StrictSlash = true
+	admin.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin: %v\n", r.URL.Path)
+	}))
+
+	r := NewRouter()
+	r.StrictSlash = true
+	r.Mount(`/admin`, admin)
+
+	cases := []routerTest{
+		{
+			path:   "/admin",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "admin: /\n",
+		},
+		{
+			path:   "/admin/",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "admin: /\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+			if rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+}
+
+func TestMountStrictSlashChild(t *testing.T) {
+	admin := NewRouter()
+	admin.StrictSlash = true
+	admin.Methods(http.MethodGet).Handler(`/users/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin users/: %v\n", r.URL.Path)
+	}))
+	admin.Methods(http.MethodGet).Handler(`/posts`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin posts: %v\n", r.URL.Path)
+	}))
+
+	r := NewRouter()
+	r.Mount(`/admin`, admin)
+
+	cases := []routerTest{
+		{
+			path:   "/admin/users/",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "admin users/: /users/\n",
+		},
+		{
+			path:   "/admin/users",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+		{
+			path:   "/admin/posts",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "admin posts: /posts\n",
+		},
+		{
+			path:   "/admin/posts/",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+			if rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+}
+
+func TestMountErrorHandlers(t *testing.T) {
+	admin := NewRouter()
+	admin.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "admin: not found %v", r.URL.Path)
+	})
+	admin.Methods(http.MethodGet).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := NewRouter()
+	r.Mount(`/admin`, admin)
+
+	cases := []routerTest{
+		{
+			path:   "/admin/users/1",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			body:   "",
+		},
+		{
+			path:   "/admin/foo",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "admin: not found /foo",
+		},
+		{
+			path:   "/admin/users/1",
+			method: http.MethodOptions,
+			code:   http.StatusNoContent,
+			body:   "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestMountRawPath(t *testing.T) {
+	r := NewRouter()
+	r.Mount(`/files/:dir`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v %v", r.URL.Path, r.URL.EscapedPath())
+	}))
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/files/docs/a%2Fb/c", expected: "/a/b/c /a%2Fb/c"},
+		{path: "/files/a%2Fb/c%20d", expected: "/b/c d /b/c%20d"},
+		{path: "/files/docs/a/b", expected: "/a/b /a/b"},
+		{path: "/files/docs/a%2Fb/", expected: "/a/b/ /a%2Fb/"},
+		{path: "/files/docs", expected: "/ /"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestStripRawPath(t *testing.T) {
+	cases := []struct {
+		rawPath  string
+		rest     string
+		expected string
+	}{
+		{rawPath: "/admin/a%2Fb", rest: "/a/b", expected: "/a%2Fb"},
+		{rawPath: "/admin/a%2Fb/c", rest: "/a/b/c", expected: "/a%2Fb/c"},
+		{rawPath: "/a%2Fb/c", rest: "/c", expected: "/c"},
+		{rawPath: "/admin%2Fusers", rest: "/users", expected: ""},
+		{rawPath: "", rest: "/a", expected: ""},
+	}
+
+	for _, c := range cases {
+		actual := stripRawPath(c.rawPath, c.rest)
+		if actual != c.expected {
+			t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package goblin
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ParamsHandlerFunc is a handler which receives the parameters of a request as an argument.
+// A ParamsHandlerFunc registered without middlewares receives the parameters without storing them in the context,
+// which avoids allocations. Then the parameters are valid only until the handler returns, and they must be copied
+// to be used after that. With middlewares, the parameters are read from the context.
+type ParamsHandlerFunc func(w http.ResponseWriter, r *http.Request, ps Params)
+
+// ServeHTTP calls f with the parameters in the context of r.
+func (f ParamsHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f(w, r, ParamsFromContext(r.Context()))
+}
+
+// paramTag is the struct tag which BindParams reads. ex. `param:"id"`
+const paramTag = "param"
+
+// NewParam creates a new parameter.
+func NewParam(key string, value string) Param {
+	return Param{
+		key:   key,
+		value: value,
+	}
+}
+
+// NewParams creates new parameters from pairs of a key and a value. ex. NewParams("id", "42", "slug", "hello")
+// It panics if pairs has an odd number of elements.
+func NewParams(pairs ...string) Params {
+	if len(pairs)%2 != 0 {
+		panic("goblin: NewParams: odd number of arguments")
+	}
+	ps := make(Params, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		ps = append(ps, NewParam(pairs[i], pairs[i+1]))
+	}
+	return ps
+}
+
+// WithParams returns a copy of ctx which has ps, so that handlers can be tested without a router.
+func WithParams(ctx context.Context, ps Params) context.Context {
+	return withParams(ctx, ps)
+}
+
+// ParamsFromContext gets the parameters from a context of a request in the order of the path.
+// It returns nil if the request has no parameters.
+func ParamsFromContext(ctx context.Context) Params {
+	switch ps := ctx.Value(ParamsKey).(type) {
+	case *Params:
+		return *ps
+	case Params:
+		return ps
+	}
+	return nil
+}
+
+// Key returns the name of the parameter.
+func (p Param) Key() string {
+	return p.key
+}
+
+// Value returns the value of the parameter.
+func (p Param) Value() string {
+	return p.value
+}
+
+// Len returns the number of the parameters.
+func (ps Params) Len() int {
+	return len(ps)
+}
+
+// Get gets the value of the parameter named name. It returns an empty string if the parameter doesn't exist.
+func (ps Params) Get(name string) string {
+	v, _ := ps.lookup(name)
+	return v
+}
+
+// lookup gets the value of the parameter named name, and reports whether the parameter exists.
+func (ps Params) lookup(name string) (string, bool) {
+	for i := 0; i < len(ps); i++ {
+		if ps[i].key == name {
+			return ps[i].value, true
+		}
+	}
+	return "", false
+}
+
+// lookupParam gets the value of a parameter from a context, and reports whether the parameter exists.
+func lookupParam(ctx context.Context, name string) (string, bool) {
+	return ParamsFromContext(ctx).lookup(name)
+}
+
+// getParamValue gets the value of a parameter from a context, or returns ErrMissingParam.
+func getParamValue(ctx context.Context, name string) (string, error) {
+	v, ok := lookupParam(ctx, name)
+	if !ok {
+		return "", fmt.Errorf("goblin: %s: %w", name, ErrMissingParam)
+	}
+	return v, nil
+}
+
+// ParamInt gets a parameter as an int.
+// It returns ErrMissingParam if the parameter doesn't exist, and ErrInvalidParam if the value isn't an int.
+func ParamInt(ctx context.Context, name string) (int, error) {
+	v, err := getParamValue(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("goblin: %s=%q: %w: %w", name, v, ErrInvalidParam, err)
+	}
+	return n, nil
+}
+
+// ParamInt64 gets a parameter as an int64.
+// It returns ErrMissingParam if the parameter doesn't exist, and ErrInvalidParam if the value isn't an int64.
+func ParamInt64(ctx context.Context, name string) (int64, error) {
+	v, err := getParamValue(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("goblin: %s=%q: %w: %w", name, v, ErrInvalidParam, err)
+	}
+	return n, nil
+}
+
+// ParamUUID gets a parameter as the bytes of a UUID in the canonical form. ex. 123e4567-e89b-12d3-a456-426614174000
+// It returns ErrMissingParam if the parameter doesn't exist, and ErrInvalidParam if the value isn't a UUID.
+func ParamUUID(ctx context.Context, name string) ([16]byte, error) {
+	v, err := getParamValue(ctx, name)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	u, ok := parseUUID(v)
+	if !ok {
+		return [16]byte{}, fmt.Errorf("goblin: %s=%q: %w: not a UUID", name, v, ErrInvalidParam)
+	}
+	return u, nil
+}
+
+// ParamTime gets a parameter as a time parsed with layout. ex. ParamTime(ctx, "date", time.DateOnly)
+// It returns ErrMissingParam if the parameter doesn't exist, and ErrInvalidParam if the value doesn't match layout.
+func ParamTime(ctx context.Context, name string, layout string) (time.Time, error) {
+	v, err := getParamValue(ctx, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("goblin: %s=%q: %w: %w", name, v, ErrInvalidParam, err)
+	}
+	return t, nil
+}
+
+// parseUUID parses a UUID in the canonical form, and reports whether s is a UUID.
+func parseUUID(s string) ([16]byte, bool) {
+	var u [16]byte
+	if !isUUID(s) {
+		return u, false
+	}
+	j := 0
+	for i := 0; i < len(s); i += 2 {
+		if s[i] == '-' {
+			i++
+		}
+		u[j] = unhex(s[i])<<4 | unhex(s[i+1])
+		j++
+	}
+	return u, true
+}
+
+// unhex converts an ASCII hexadecimal digit c to its value.
+func unhex(c byte) byte {
+	switch {
+	case isDigit(c):
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// BindParams sets parameters to the fields of the struct which dst points to, according to `param` tags of the fields.
+// ex.
+//
+//	type UserPostParams struct {
+//		UserID int    `param:"id"`
+//		Slug   string `param:"slug"`
+//	}
+//
+// A field whose parameter doesn't exist is left unchanged, and fields of embedded structs are set as well.
+// A field can be a string, a bool, an integer, a float, a [16]byte for a UUID,
+// or a type which implements encoding.TextUnmarshaler such as time.Time.
+// It returns ErrInvalidParam if a value can't be converted to the type of the field.
+func BindParams(ctx context.Context, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("goblin: BindParams: dst must be a non-nil pointer to a struct, not %T", dst)
+	}
+	return bindParams(ctx, rv.Elem())
+}
+
+// bindParams sets parameters to the fields of the struct v.
+func bindParams(ctx context.Context, v reflect.Value) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, ok := f.Tag.Lookup(paramTag)
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				if err := bindParams(ctx, v.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		value, ok := lookupParam(ctx, name)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("goblin: %s=%q: %w: %s: %w", name, value, ErrInvalidParam, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField converts value to the type of the field fv and sets it.
+func setField(fv reflect.Value, value string) error {
+	if tu, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(value))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Array:
+		if fv.Len() != 16 || fv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		u, ok := parseUUID(value)
+		if !ok {
+			return fmt.Errorf("not a UUID")
+		}
+		fv.Set(reflect.ValueOf(u).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
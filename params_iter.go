@@ -0,0 +1,16 @@
+//go:build go1.23
+
+package goblin
+
+import "iter"
+
+// All returns an iterator over the keys and the values of the parameters in the order of the path.
+func (ps Params) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, p := range ps {
+			if !yield(p.key, p.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package goblin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParamsAll(t *testing.T) {
+	ps := NewParams("tenant", "acme", "id", "42", "slug", "hello")
+
+	var actual []string
+	for k, v := range ps.All() {
+		actual = append(actual, k+"="+v)
+	}
+	expected := []string{"tenant=acme", "id=42", "slug=hello"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual: %v expected: %v\n", actual, expected)
+	}
+
+	// The iteration stops when the loop breaks.
+	actual = nil
+	for k := range ps.All() {
+		actual = append(actual, k)
+		break
+	}
+	if !reflect.DeepEqual(actual, []string{"tenant"}) {
+		t.Errorf("actual: %v expected: %v\n", actual, []string{"tenant"})
+	}
+}
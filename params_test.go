@@ -0,0 +1,262 @@
+package goblin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newParamsContext(pairs ...string) context.Context {
+	return WithParams(context.Background(), NewParams(pairs...))
+}
+
+func TestParamsFromContext(t *testing.T) {
+	ctx := newParamsContext("id", "42", "slug", "hello")
+
+	ps := ParamsFromContext(ctx)
+	if ps.Len() != 2 {
+		t.Fatalf("actual: %v expected: %v\n", ps.Len(), 2)
+	}
+	if ps[0].Key() != "id" || ps[0].Value() != "42" {
+		t.Errorf("actual: %v=%v expected: %v=%v\n", ps[0].Key(), ps[0].Value(), "id", "42")
+	}
+	if ps[1] != NewParam("slug", "hello") {
+		t.Errorf("actual: %v expected: %v\n", ps[1], NewParam("slug", "hello"))
+	}
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "id", expected: "42"},
+		{name: "slug", expected: "hello"},
+		{name: "none", expected: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := ps.Get(c.name); actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+			if actual := GetParam(ctx, c.name); actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+
+	// A context without parameters has no parameters.
+	if ps := ParamsFromContext(context.Background()); ps != nil || ps.Len() != 0 || ps.Get("id") != "" {
+		t.Errorf("actual: %v expected: %v\n", ps, nil)
+	}
+}
+
+func TestNewParamsOddArguments(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("actual: %v expected: a panic\n", nil)
+		}
+	}()
+	NewParams("id")
+}
+
+func TestParamInt(t *testing.T) {
+	ctx := newParamsContext("id", "42", "name", "john", "big", "9223372036854775807")
+
+	cases := []struct {
+		name     string
+		expected int
+		err      error
+	}{
+		{name: "id", expected: 42},
+		{name: "name", err: ErrInvalidParam},
+		{name: "none", err: ErrMissingParam},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParamInt(ctx, c.name)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+
+	// The error of strconv is wrapped.
+	_, err := ParamInt(ctx, "name")
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("actual: %v expected: %v\n", err, strconv.ErrSyntax)
+	}
+}
+
+func TestParamInt64(t *testing.T) {
+	ctx := newParamsContext("big", "9223372036854775807", "over", "9223372036854775808")
+
+	cases := []struct {
+		name     string
+		expected int64
+		err      error
+	}{
+		{name: "big", expected: 9223372036854775807},
+		{name: "over", err: ErrInvalidParam},
+		{name: "none", err: ErrMissingParam},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParamInt64(ctx, c.name)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestParamUUID(t *testing.T) {
+	ctx := newParamsContext("id", "123e4567-E89B-12d3-a456-426614174000", "name", "john")
+
+	cases := []struct {
+		name     string
+		expected [16]byte
+		err      error
+	}{
+		{name: "id", expected: [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}},
+		{name: "name", err: ErrInvalidParam},
+		{name: "none", err: ErrMissingParam},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParamUUID(ctx, c.name)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestParamTime(t *testing.T) {
+	ctx := newParamsContext("date", "2024-02-29", "name", "john")
+
+	cases := []struct {
+		name     string
+		expected time.Time
+		err      error
+	}{
+		{name: "date", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "name", err: ErrInvalidParam},
+		{name: "none", err: ErrMissingParam},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParamTime(ctx, c.name, time.DateOnly)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if !actual.Equal(c.expected) {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+type uuid [16]byte
+
+type bindEmbedded struct {
+	Page uint8 `param:"page"`
+}
+
+type bindTarget struct {
+	bindEmbedded
+	ID       int       `param:"id"`
+	Slug     string    `param:"slug"`
+	Draft    bool      `param:"draft"`
+	Score    float64   `param:"score"`
+	UUID     uuid      `param:"uuid"`
+	Date     time.Time `param:"date"`
+	IP       net.IP    `param:"ip"`
+	Missing  string    `param:"missing"`
+	Ignored  string    `param:"-"`
+	Untagged string
+}
+
+func TestBindParams(t *testing.T) {
+	ctx := newParamsContext(
+		"id", "42",
+		"slug", "hello-world",
+		"draft", "true",
+		"score", "1.5",
+		"uuid", "123e4567-e89b-12d3-a456-426614174000",
+		"date", "2024-02-29T10:00:00Z",
+		"ip", "127.0.0.1",
+		"page", "3",
+		"-", "ignored",
+	)
+
+	actual := bindTarget{Missing: "default"}
+	if err := BindParams(ctx, &actual); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+	expected := bindTarget{
+		bindEmbedded: bindEmbedded{Page: 3},
+		ID:           42,
+		Slug:         "hello-world",
+		Draft:        true,
+		Score:        1.5,
+		UUID:         uuid{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00},
+		Date:         time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC),
+		IP:           net.ParseIP("127.0.0.1"),
+		Missing:      "default",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual: %v expected: %v\n", actual, expected)
+	}
+}
+
+func TestBindParamsFailure(t *testing.T) {
+	var target bindTarget
+	var n int
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		dst  any
+		err  error
+	}{
+		{name: "invalid int", ctx: newParamsContext("id", "john"), dst: &target, err: ErrInvalidParam},
+		{name: "overflow", ctx: newParamsContext("page", "256"), dst: &target, err: ErrInvalidParam},
+		{name: "invalid uuid", ctx: newParamsContext("uuid", "john"), dst: &target, err: ErrInvalidParam},
+		{name: "invalid time", ctx: newParamsContext("date", "john"), dst: &target, err: ErrInvalidParam},
+		{name: "unsupported type", ctx: newParamsContext("ch", "1"), dst: &struct {
+			Ch chan int `param:"ch"`
+		}{}, err: ErrInvalidParam},
+		{name: "not a pointer", ctx: newParamsContext(), dst: target},
+		{name: "not a struct", ctx: newParamsContext(), dst: &n},
+		{name: "nil", ctx: newParamsContext(), dst: (*bindTarget)(nil)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := BindParams(c.ctx, c.dst)
+			if err == nil {
+				t.Fatalf("actual: %v expected: an error\n", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Errorf("actual: %v expected: %v\n", err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package goblin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	leftBraceDelimiter  string = "{"
+	rightBraceDelimiter string = "}"
+	braceCatchAllSuffix string = "..."
+	braceEndOfPath      string = "{$}"
+)
+
+// subtreeParamName is the name of the catch-all parameter which captures the path under a pattern of Handle
+// which ends with a slash.
+const subtreeParamName = "goblinSubtreePath"
+
+// allMethods are the methods which a route without methods handles.
+var allMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// Handle registers h for pattern, which has the syntax of http.ServeMux. ex. GET /items/{id}
+// A pattern is [METHOD ][HOST]/[PATH]. A pattern without a method handles all methods,
+// and a pattern with a host is registered to the router of Host.
+// A path can have parameters of goblin and parameters enclosed in braces. See parsePattern for the syntax.
+// As http.ServeMux, a path which ends with a slash matches the paths under it, and a path which ends with {$}
+// matches only the path with the trailing slash. ex. /static/ matches /static/css/main.css
+// {$} follows the trailing slash policy of the router, so that /items/{$} matches /items as well
+// unless StrictSlash is set.
+func (r *Router) Handle(pattern string, h http.Handler) {
+	method, host, path, err := splitPattern(pattern)
+	if err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.reportErr(fmt.Errorf("goblin: %s: %w", pattern, err))
+		return
+	}
+
+	router := r
+	if host != "" {
+		router = r.Host(host)
+	}
+	methods := allMethods
+	if method != "" {
+		methods = []string{method}
+	}
+	// The path is registered with a catch-all parameter, and it is shown as it is.
+	// ex. /static/ → /static/{goblinSubtreePath...}
+	full := path
+	if strings.HasSuffix(path, "/") {
+		full = path + leftBraceDelimiter + subtreeParamName + braceCatchAllSuffix + rightBraceDelimiter
+	}
+	router.Methods(methods...).register(full, path, h, false)
+}
+
+// HandleFunc registers the handler function h for pattern like Handle.
+func (r *Router) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(h))
+}
+
+// splitPattern splits a pattern of http.ServeMux into the method, the host and the path.
+// ex.
+// GET example.com/items/{id} → GET, example.com, /items/{id}
+// /items/{id}                → "", "", /items/{id}
+func splitPattern(pattern string) (method string, host string, path string, err error) {
+	rest := pattern
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		method, rest = pattern[:i], strings.TrimLeft(pattern[i+1:], " \t")
+		if method == "" || strings.Contains(method, "/") {
+			return "", "", "", fmt.Errorf("%w: %q isn't a valid method", ErrInvalidPattern, method)
+		}
+	}
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return "", "", "", fmt.Errorf("%w: %q doesn't have a path", ErrInvalidPattern, rest)
+	}
+	return method, rest[:i], rest[i:], nil
+}
+
+// parsePattern converts parameters enclosed in braces in path to parameters of goblin.
+// A parameter enclosed in braces must be a whole segment, and a pattern of a parameter matches the whole segment.
+// Parameters of goblin are kept as they are.
+// ex.
+// /items/{id}        → /items/:id
+// /items/{id:[0-9]+} → /items/:id[^(?:[0-9]+)$]
+// /files/{path...}   → /files/*path
+// /items/{$}         → /items/
+func parsePattern(path string) (string, error) {
+	if !strings.Contains(path, leftBraceDelimiter) {
+		return path, nil
+	}
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, paramDelimiter) || strings.HasPrefix(seg, catchAllDelimiter):
+			continue
+		case seg == braceEndOfPath:
+			if i != len(segments)-1 {
+				return "", fmt.Errorf("%w: %s must be at the end of path", ErrInvalidPattern, seg)
+			}
+			segments[i] = ""
+		case strings.HasPrefix(seg, leftBraceDelimiter) && strings.HasSuffix(seg, rightBraceDelimiter):
+			label, err := parseBraceParam(seg)
+			if err != nil {
+				return "", err
+			}
+			segments[i] = label
+		case strings.Contains(seg, leftBraceDelimiter):
+			return "", fmt.Errorf("%w: %s must be a whole segment", ErrInvalidPattern, seg)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// parseBraceParam converts a parameter enclosed in braces to a parameter label of goblin.
+// A pattern is anchored explicitly, so that it matches a whole segment even if SubstringPatterns is set.
+// ex.
+// {id}        → :id
+// {id:[0-9]+} → :id[^(?:[0-9]+)$]
+// {path...}   → *path
+func parseBraceParam(seg string) (string, error) {
+	inner := seg[len(leftBraceDelimiter) : len(seg)-len(rightBraceDelimiter)]
+
+	if name, ok := strings.CutSuffix(inner, braceCatchAllSuffix); ok {
+		if !isBraceParamName(name) {
+			return "", fmt.Errorf("%w: %s doesn't have a valid name", ErrInvalidPattern, seg)
+		}
+		return catchAllDelimiter + name, nil
+	}
+
+	name, ptn, ok := strings.Cut(inner, ":")
+	if !isBraceParamName(name) {
+		return "", fmt.Errorf("%w: %s doesn't have a valid name", ErrInvalidPattern, seg)
+	}
+	if !ok {
+		return paramDelimiter + name, nil
+	}
+	if ptn == "" {
+		return "", fmt.Errorf("%w: %s has an empty pattern", ErrInvalidPattern, seg)
+	}
+	return paramDelimiter + name + leftPtnDelimiter + "^(?:" + ptn + ")$" + rightPtnDelimiter, nil
+}
+
+// isBraceParamName reports whether name can be a name of a parameter enclosed in braces.
+func isBraceParamName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "{}[]:*")
+}
@@ -0,0 +1,213 @@
+package goblin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	cases := []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{path: `/`, expected: `/`},
+		{path: `/items/:id[^\d+$]`, expected: `/items/:id[^\d+$]`},
+		{path: `/items/:id[^\d{3}$]`, expected: `/items/:id[^\d{3}$]`},
+		{path: `/items/{id}`, expected: `/items/:id`},
+		{path: `/items/{id}/posts/{slug}`, expected: `/items/:id/posts/:slug`},
+		{path: `/items/{id:[0-9]+}`, expected: `/items/:id[^(?:[0-9]+)$]`},
+		{path: `/items/{id:[0-9]{3}}`, expected: `/items/:id[^(?:[0-9]{3})$]`},
+		{path: `/files/{path...}`, expected: `/files/*path`},
+		{path: `/items/{$}`, expected: `/items/`},
+		{path: `/{$}`, expected: `/`},
+		{path: `/items/{}`, err: ErrInvalidPattern},
+		{path: `/items/{:[0-9]+}`, err: ErrInvalidPattern},
+		{path: `/items/{id:}`, err: ErrInvalidPattern},
+		{path: `/files/{...}`, err: ErrInvalidPattern},
+		{path: `/items/{id}.json`, err: ErrInvalidPattern},
+		{path: `/items/{id`, err: ErrInvalidPattern},
+		{path: `/items/{$}/foo`, err: ErrInvalidPattern},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			actual, err := parsePattern(c.path)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestSplitPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		method  string
+		host    string
+		path    string
+		err     error
+	}{
+		{pattern: `/items/{id}`, path: `/items/{id}`},
+		{pattern: `GET /items/{id}`, method: http.MethodGet, path: `/items/{id}`},
+		{pattern: "POST \t /items", method: http.MethodPost, path: `/items`},
+		{pattern: `example.com/items`, host: `example.com`, path: `/items`},
+		{pattern: `GET {tenant}.example.com/`, method: http.MethodGet, host: `{tenant}.example.com`, path: `/`},
+		{pattern: `GET`, err: ErrInvalidPattern},
+		{pattern: `GET items`, err: ErrInvalidPattern},
+		{pattern: ` /items`, err: ErrInvalidPattern},
+		{pattern: `/a /items`, err: ErrInvalidPattern},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			method, host, path, err := splitPattern(c.pattern)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if method != c.method || host != c.host || path != c.path {
+				t.Errorf("actual: %v %v %v expected: %v %v %v\n", method, host, path, c.method, c.host, c.path)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	paramsHandler := func(names ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+			for _, n := range names {
+				fmt.Fprintf(w, " %s=%s", n, GetParam(r.Context(), n))
+			}
+		}
+	}
+
+	r := NewRouter()
+	r.Handle(`GET /items/{id:[0-9]+}`, paramsHandler("id"))
+	r.Handle(`GET /items/{slug}`, paramsHandler("slug"))
+	r.Handle(`GET /items/{$}`, paramsHandler())
+	r.Handle(`/files/{path...}`, paramsHandler("path"))
+	r.HandleFunc(`GET {tenant}.example.com/users/{id}`, paramsHandler("tenant", "id"))
+	r.Group(`/tenants/{tenant}`, func(g *Group) {
+		g.Methods(http.MethodGet).Handler(`/users/{id}`, paramsHandler("tenant", "id"))
+	})
+	if err := r.Validate(); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+
+	cases := []struct {
+		method string
+		host   string
+		path   string
+		code   int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/items/123", code: http.StatusOK, body: "GET /items/123 id=123"},
+		{method: http.MethodGet, path: "/items/a123", code: http.StatusOK, body: "GET /items/a123 slug=a123"},
+		{method: http.MethodGet, path: "/items/", code: http.StatusOK, body: "GET /items/"},
+		{method: http.MethodPost, path: "/items/123", code: http.StatusMethodNotAllowed, body: ""},
+		{method: http.MethodGet, path: "/files/css/main.css", code: http.StatusOK, body: "GET /files/css/main.css path=css/main.css"},
+		{method: http.MethodDelete, path: "/files/css/main.css", code: http.StatusOK, body: "DELETE /files/css/main.css path=css/main.css"},
+		{method: http.MethodGet, path: "/files/", code: http.StatusOK, body: "GET /files/ path="},
+		{method: http.MethodGet, host: "acme.example.com", path: "/users/1", code: http.StatusOK, body: "GET /users/1 tenant=acme id=1"},
+		{method: http.MethodGet, path: "/tenants/acme/users/1", code: http.StatusOK, body: "GET /tenants/acme/users/1 tenant=acme id=1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+"_"+c.host+c.path, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			if c.host != "" {
+				req.Host = c.host
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+			if rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+
+	// {$} follows the trailing slash policy of the router.
+	for _, c := range []struct {
+		strictSlash bool
+		path        string
+		code        int
+	}{
+		{strictSlash: false, path: "/a/", code: http.StatusOK},
+		{strictSlash: false, path: "/a", code: http.StatusOK},
+		{strictSlash: false, path: "/a/b", code: http.StatusNotFound},
+		{strictSlash: true, path: "/a/", code: http.StatusOK},
+		{strictSlash: true, path: "/a", code: http.StatusNotFound},
+		{strictSlash: true, path: "/a/b", code: http.StatusNotFound},
+	} {
+		r := NewRouter()
+		r.StrictSlash = c.strictSlash
+		r.Handle(`GET /a/{$}`, paramsHandler())
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != c.code {
+			t.Errorf("%s %t actual: %v expected: %v\n", c.path, c.strictSlash, rec.Code, c.code)
+		}
+	}
+
+	// A path which ends with a slash matches the paths under it.
+	r = NewRouter()
+	r.Handle(`GET /static/`, paramsHandler())
+	r.Handle(`GET /static/{$}`, paramsHandler("index"))
+	r.Handle(`/`, paramsHandler())
+	if err := r.Validate(); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+	for _, c := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/static/css/main.css", body: "GET /static/css/main.css"},
+		{method: http.MethodGet, path: "/static/", body: "GET /static/ index="},
+		{method: http.MethodGet, path: "/static", body: "GET /static index="},
+		{method: http.MethodPost, path: "/static/css/main.css", body: "POST /static/css/main.css"},
+		{method: http.MethodGet, path: "/", body: "GET /"},
+		{method: http.MethodGet, path: "/foo/bar", body: "GET /foo/bar"},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != c.body {
+			t.Errorf("%s %s actual: %v %v expected: %v %v\n", c.method, c.path, rec.Code, rec.Body.String(), http.StatusOK, c.body)
+		}
+	}
+	expected := []RouteInfo{
+		{Method: http.MethodGet, Pattern: "/static/", Params: []string{subtreeParamName}},
+		{Method: http.MethodGet, Pattern: "/static/{$}"},
+	}
+	var actual []RouteInfo
+	for _, ri := range r.Routes() {
+		if ri.Method == http.MethodGet && strings.HasPrefix(ri.Pattern, "/static") {
+			actual = append(actual, ri)
+		}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual: %v expected: %v\n", actual, expected)
+	}
+
+	// Invalid patterns are reported.
+	r = NewRouter()
+	r.Handle(`GET items`, paramsHandler())
+	r.Handle(`GET /items/{id}.json`, paramsHandler())
+	if err := r.Validate(); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("actual: %v expected: %v\n", err, ErrInvalidPattern)
+	}
+}
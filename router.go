@@ -3,126 +3,617 @@ package goblin
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Router represents the router which handles routing.
 type Router struct {
-	tree                    map[string]*tree
 	NotFoundHandler         http.Handler
 	MethodNotAllowedHandler http.Handler
 	DefaultOPTIONSHandler   http.Handler
-	globalMiddlewares       middlewares
+	// StrictRegistration makes a registration panic if the route is invalid or conflicts with registered routes.
+	StrictRegistration bool
+	// ImplicitHEAD makes a HEAD request which doesn't match any HEAD routes handled by the matching GET route.
+	// The response body is discarded.
+	ImplicitHEAD bool
+	// StrictSlash makes a path with a trailing slash and a path without it different routes. ex. /foo/ and /foo
+	StrictSlash bool
+	// RedirectTrailingSlash redirects a request to the path with or without a trailing slash if the path matches a route.
+	// It implies StrictSlash.
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects a request to the cleaned path if the path matches a route. ex. /foo/../bar → /bar
+	// Otherwise the path is cleaned silently.
+	RedirectFixedPath bool
+	// CaseInsensitive makes static segments of a path match case-insensitively. ex. /USERS/42 → /users/:id
+	// Only ASCII letters are folded, and parameter values keep their original case.
+	CaseInsensitive bool
+	// RedirectFixedCase redirects a request to the path of the route which matches the path case-insensitively.
+	// ex. /USERS/42 → /users/42
+	// Parameter values keep their original case. It has no effect if CaseInsensitive is set.
+	RedirectFixedCase bool
+	// SaveMatchedRoute saves the matched route in the context of a request, which can be read with RouteFromContext.
+	// It costs an allocation per request.
+	SaveMatchedRoute bool
+	// SetPathValue sets parameters to a request with Request.SetPathValue, which can be read with Request.PathValue
+	// as well as GetParam. It costs allocations per request.
+	SetPathValue bool
+	// SubstringPatterns makes regular expressions of parameters match a substring of a segment as in older versions,
+	// unless they are anchored with ^ and $. ex. :id[\d+] matches abc1def
+	// By default, a regular expression matches a whole segment, and a regular expression which starts with ~
+	// matches a substring of a segment. ex. :id[~\d+]
+	// It is for migration, and it must be set before routes are registered.
+	SubstringPatterns bool
+	table             atomic.Pointer[routeTable]
+	names             map[string]namedRoute
+	errs              []error
+	parent            *Router    // router which created the router with Host. Its settings are used.
+	mu                sync.Mutex // guards registrations
 }
 
-// route represents the route which has data for a routing.
-type route struct {
+// Route represents the route which has data for a routing.
+// A Route is created for each registration, so that registrations don't affect each other.
+type Route struct {
+	router      *Router
+	group       *Group
+	name        string
 	methods     []string
-	path        string
-	handler     http.Handler
 	middlewares middlewares
 }
 
 var (
-	tmpRoute = &route{}
-
 	// Error for not found.
 	ErrNotFound = errors.New("no matching route was found")
 	// Error for method not allowed.
 	ErrMethodNotAllowed = errors.New("methods is not allowed")
+	// Error for a route which is already registered.
+	ErrDuplicateRoute = errors.New("route is already registered")
+	// Error for a route which matches the same paths as a registered route.
+	ErrAmbiguousRoute = errors.New("route is ambiguous with a registered route")
+	// Error for a malformed route pattern.
+	ErrInvalidPattern = errors.New("route pattern is invalid")
+	// Error for a route name which is not registered.
+	ErrUnknownRoute = errors.New("no route has the name")
+	// Error for a parameter which is required to build a URL but not given.
+	ErrMissingParam = errors.New("parameter is missing")
+	// Error for a parameter value which can't be used to build a URL.
+	ErrInvalidParam = errors.New("parameter is invalid")
 )
 
 // NewRouter creates a new router.
 func NewRouter() *Router {
-	return &Router{
-		tree: map[string]*tree{},
-	}
+	r := &Router{}
+	r.table.Store(&routeTable{
+		trees: map[string]*tree{},
+	})
+	return r
 }
 
 func (r *Router) UseGlobal(mws ...middleware) {
 	nm := NewMiddlewares(mws)
-	r.globalMiddlewares = nm
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.updateTable(func(t *routeTable) {
+		t.globalMiddlewares = nm
+	})
+}
+
+// newRoute creates a new route.
+func (r *Router) newRoute() *Route {
+	return &Route{
+		router: r,
+	}
+}
+
+// Use creates a new route and sets middlewares.
+func (r *Router) Use(mws ...middleware) *Route {
+	return r.newRoute().Use(mws...)
+}
+
+// Methods creates a new route and sets methods.
+func (r *Router) Methods(methods ...string) *Route {
+	return r.newRoute().Methods(methods...)
 }
 
 // Use sets middlewares.
-func (r *Router) Use(mws ...middleware) *Router {
-	nm := NewMiddlewares(mws)
-	tmpRoute.middlewares = nm
-	return r
+func (rt *Route) Use(mws ...middleware) *Route {
+	rt.middlewares = NewMiddlewares(mws)
+	return rt
 }
 
-// Use sets methods.
-func (r *Router) Methods(methods ...string) *Router {
-	tmpRoute.methods = append(tmpRoute.methods, methods...)
-	return r
+// Methods sets methods.
+func (rt *Route) Methods(methods ...string) *Route {
+	rt.methods = append(rt.methods, methods...)
+	return rt
+}
+
+// Name sets a name of the route, which is used to build the URL of the route with URL.
+func (rt *Route) Name(name string) *Route {
+	rt.name = name
+	return rt
 }
 
-// Handler sets a handler.
-func (r Router) Handler(path string, handler http.Handler) {
-	tmpRoute.handler = handler
-	tmpRoute.path = path
-	r.Handle()
+// Handler sets a handler and registers the route to the router.
+// If the route belongs to a group, the prefix and the middlewares of the group are added.
+func (rt *Route) Handler(path string, handler http.Handler) {
+	rt.register(path, path, handler, false)
 }
 
-// Handle handles a route.
-func (r *Router) Handle() {
-	for i := 0; i < len(tmpRoute.methods); i++ {
-		_, ok := r.tree[tmpRoute.methods[i]]
-		if !ok {
-			r.tree[tmpRoute.methods[i]] = newTree()
+// Replace registers the route like Handler, but replaces the registered route of the same path without reporting ErrDuplicateRoute.
+// Requests being served keep using the replaced route, and subsequent requests use the new route.
+func (rt *Route) Replace(path string, handler http.Handler) {
+	rt.register(path, path, handler, true)
+}
+
+// register registers the route of path to the router. written is the path as registered, which is shown to users.
+func (rt *Route) register(path string, written string, handler http.Handler, replace bool) {
+	mws := rt.middlewares
+	if rt.group != nil {
+		mws = append(rt.group.allMiddlewares(), rt.middlewares...)
+	}
+	// The path as registered is shown instead of the converted path. ex. /items/{id} → /items/:id
+	written = rt.group.writtenPath(written)
+	path = rt.group.path(path)
+	// An invalid path is kept as it is, and it is reported by handle.
+	if p, err := parsePattern(path); err == nil {
+		path = p
+	}
+	if written = cleanPath(written); written == cleanPath(path) {
+		written = ""
+	}
+	rt.router.handle(rt.methods, path, action{
+		middlewares: mws,
+		handler:     handler,
+		written:     written,
+		name:        rt.name,
+	}, replace)
+	if rt.name != "" {
+		rt.router.nameRoute(rt.name, path)
+	}
+}
+
+// handle registers a route with a copy of a to the tree of each method, and publishes the new routes at once.
+// If replace is true, ErrDuplicateRoute isn't reported.
+func (r *Router) handle(methods []string, path string, a action, replace bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.updateTable(func(t *routeTable) {
+		for _, m := range methods {
+			tr, ok := t.trees[m]
+			if ok {
+				tr = tr.clone()
+			} else {
+				tr = newTree()
+			}
+			t.trees[m] = tr
+			ac := a
+			// A path with a trailing slash and the path without it are the same route unless StrictSlash is set,
+			// so that the route replaces the other like a route of the same path. ex. /foo and /foo/
+			other, dup := "", false
+			if s := r.settings(); !s.StrictSlash && !s.RedirectTrailingSlash {
+				other, dup = removeSlashVariant(tr, path)
+			}
+			err := tr.insert(path, &ac, t.constraints, r.settings().SubstringPatterns)
+			if err == nil && dup {
+				err = fmt.Errorf("%w: %s is the same route as %s", ErrDuplicateRoute, path, other)
+			}
+			if err != nil {
+				if replace && errors.Is(err, ErrDuplicateRoute) {
+					continue
+				}
+				r.reportErr(fmt.Errorf("goblin: %s %s: %w", m, path, err))
+			}
 		}
-		r.tree[tmpRoute.methods[i]].Insert(tmpRoute.path, tmpRoute.handler, tmpRoute.middlewares)
+	})
+}
+
+// removeSlashVariant removes the route of path with or without a trailing slash from t.
+// It returns the removed path, and reports whether the route was registered. ex. /foo/ for /foo
+func removeSlashVariant(t *tree, path string) (string, bool) {
+	p, err := parsePattern(path)
+	if err != nil || p == "" || p == "/" {
+		return "", false
 	}
-	tmpRoute = &route{}
+	p = toggleTrailingSlash(p)
+	return p, t.Remove(p)
+}
+
+// reportErr panics with err if StrictRegistration is set, otherwise records err for Validate.
+// It must be called with r.mu held.
+func (r *Router) reportErr(err error) {
+	if r.settings().StrictRegistration {
+		panic(err)
+	}
+	r.errs = append(r.errs, err)
+}
+
+// Validate returns errors of the registered routes which are invalid or conflict with other routes,
+// including the routes of the host routers.
+// It returns nil if all routes are registered without any problems.
+func (r *Router) Validate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := r.errs
+	for _, hr := range r.loadTable().hosts {
+		if err := hr.router.Validate(); err != nil {
+			errs = append(errs[:len(errs):len(errs)], err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // ServeHTTP dispatches the request to the handler whose
 // pattern most closely matches the request URL.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	method := req.Method
-	if method == http.MethodOptions {
-		if r.DefaultOPTIONSHandler != nil {
-			r.DefaultOPTIONSHandler.ServeHTTP(w, req)
-			return
-		}
+	// The request is served with a single table, even if routes are changed while it is served.
+	r.settings().serve(w, req, r.loadTable())
+}
+
+// settings returns the router whose settings are used for r. It is the router which created r with Host,
+// otherwise r itself.
+func (r *Router) settings() *Router {
+	for r.parent != nil {
+		r = r.parent
 	}
+	return r
+}
 
-	t, ok := r.tree[method]
-	if !ok {
-		if r.MethodNotAllowedHandler == nil {
-			methodNotAllowedHandler().ServeHTTP(w, req)
-			return
-		}
-		r.MethodNotAllowedHandler.ServeHTTP(w, req)
+// serve dispatches the request to the handler of the routes of t with the settings of r.
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, t *routeTable) {
+	if len(t.hosts) > 0 && r.serveHost(w, req, t) {
 		return
 	}
 
-	action, params, err := t.Search(req.URL.Path)
+	method := req.Method
+	m, err := r.search(t, method, req.URL.Path, r.CaseInsensitive)
+	if err == ErrNotFound && method == http.MethodHead && r.ImplicitHEAD {
+		m, err = r.search(t, http.MethodGet, req.URL.Path, r.CaseInsensitive)
+		if err == nil {
+			method = http.MethodGet
+			hw := &headResponseWriter{ResponseWriter: w}
+			defer hw.flush()
+			w = hw
+		}
+	}
 	if err == ErrNotFound {
-		if r.NotFoundHandler == nil {
-			http.NotFoundHandler().ServeHTTP(w, req)
+		if r.redirect(w, req, t) {
 			return
 		}
-		r.NotFoundHandler.ServeHTTP(w, req)
+		r.serveNoMatch(w, req, t)
 		return
 	}
+	// The pooled parameters are released after the handler returns.
+	defer m.release()
 
+	action := m.action
 	h := action.handler
-	// append globalMiddlewares to head of middlewares.
-	mws := append(r.globalMiddlewares, action.middlewares...)
+	// globalMiddlewares come first. A new slice is made to join them, so that the published table isn't modified.
+	mws := action.middlewares
+	if g := t.globalMiddlewares; len(g) > 0 {
+		mws = g
+		if len(action.middlewares) > 0 {
+			mws = append(append(make(middlewares, 0, len(g)+len(action.middlewares)), g...), action.middlewares...)
+		}
+	}
+	// A ParamsHandlerFunc without middlewares receives the parameters directly instead of the context.
+	f, direct := h.(ParamsHandlerFunc)
+	direct = direct && len(mws) == 0
 	if mws != nil {
 		h = mws.then(h)
 	}
-	if params != nil {
-		ctx := context.WithValue(req.Context(), ParamsKey, params)
+	ctx := req.Context()
+	params := m.params()
+	// Parameters captured by a parent router which mounts r come first. ex. /tenants/:tenant
+	if direct {
+		if parent := ParamsFromContext(ctx); len(parent) > 0 {
+			params = mergeParams(parent, params)
+		}
+	} else if params != nil {
+		// The context can outlive the handler, so that it has a copy of the pooled parameters.
+		params = mergeParams(ParamsFromContext(ctx), params)
+		ctx = withParams(ctx, params)
+	}
+	if r.SaveMatchedRoute {
+		ctx = context.WithValue(ctx, matchedRouteKey{}, &MatchedRoute{
+			Method:  method,
+			Pattern: action.routePattern(),
+			Name:    action.name,
+		})
+	}
+	if r.SetPathValue {
+		// The request is copied deeply, so that the path values of the request of the caller aren't modified.
+		req = req.Clone(ctx)
+		for _, p := range params {
+			req.SetPathValue(p.key, p.value)
+		}
+	} else if ctx != req.Context() {
 		req = req.WithContext(ctx)
 	}
+	if direct {
+		f(w, req, params)
+		return
+	}
 	h.ServeHTTP(w, req)
 }
 
+// routeMatch is a route which matches a path.
+// The parameters are pooled by the tree, and must be released after use.
+type routeMatch struct {
+	action *action
+	ps     *Params
+	tree   *tree
+}
+
+// params returns the pooled parameters. It returns nil if the path has no parameters.
+func (m routeMatch) params() Params {
+	if m.ps == nil {
+		return nil
+	}
+	return *m.ps
+}
+
+// release puts the pooled parameters back to the tree.
+func (m routeMatch) release() {
+	if m.ps != nil {
+		m.tree.putParams(m.ps)
+	}
+}
+
+// mergeParams returns a new slice which has parent and ps in order.
+func mergeParams(parent Params, ps Params) Params {
+	return append(append(make(Params, 0, len(parent)+len(ps)), parent...), ps...)
+}
+
+// search searches a path from the tree of the method in the table according to the trailing slash and the path cleaning policies.
+// If fold is true, static segments of the path match case-insensitively.
+// The match must be released after use.
+func (r *Router) search(tbl *routeTable, method string, path string, fold bool) (routeMatch, error) {
+	t, ok := tbl.trees[method]
+	if !ok {
+		return routeMatch{}, ErrNotFound
+	}
+
+	cp := cleanPath(path)
+	if r.RedirectFixedPath && cp != path {
+		// The path must be redirected to the cleaned path.
+		return routeMatch{}, ErrNotFound
+	}
+	var opts searchOption
+	if fold {
+		opts |= searchFold
+	}
+	if !r.StrictSlash && !r.RedirectTrailingSlash {
+		if cp != "/" && strings.HasSuffix(cp, "/") {
+			cp = removeTrailingSlash(cp)
+			opts |= searchSlash
+		}
+		opts |= searchTSR
+	}
+	a, ps, err := t.find(cp, opts)
+	return routeMatch{action: a, ps: ps, tree: t}, err
+}
+
+// lookup searches a path like search, but falls back to the GET route for a HEAD request if ImplicitHEAD is set.
+func (r *Router) lookup(t *routeTable, method string, path string, fold bool) (routeMatch, error) {
+	m, err := r.search(t, method, path, fold)
+	if err == ErrNotFound && method == http.MethodHead && r.ImplicitHEAD {
+		return r.search(t, http.MethodGet, path, fold)
+	}
+	return m, err
+}
+
+// redirect redirects a request to the canonical path if the canonical path matches a route of t.
+// It reports whether the request is redirected.
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, t *routeTable) bool {
+	fixCase := r.RedirectFixedCase && !r.CaseInsensitive
+	if !r.RedirectFixedPath && !r.RedirectTrailingSlash && !fixCase {
+		return false
+	}
+
+	path := req.URL.Path
+	candidates := make([]string, 0, 2)
+	cp := cleanPath(path)
+	if r.RedirectFixedPath && cp != path {
+		candidates = append(candidates, cp)
+	}
+	if r.RedirectTrailingSlash && cp != "/" {
+		candidates = append(candidates, toggleTrailingSlash(cp))
+	}
+
+	for _, c := range candidates {
+		if m, err := r.lookup(t, req.Method, c, r.CaseInsensitive); err == nil {
+			m.release()
+			redirectTo(w, req, c)
+			return true
+		}
+	}
+
+	if fixCase {
+		// The path of the route is built from the registered pattern, so that parameter values keep their case.
+		candidates = append(candidates[:0], cp)
+		if r.RedirectTrailingSlash && cp != "/" {
+			candidates = append(candidates, toggleTrailingSlash(cp))
+		}
+		for _, c := range candidates {
+			m, err := r.lookup(t, req.Method, c, true)
+			if err != nil {
+				continue
+			}
+			p := fillPattern(m.action.pattern, m.params())
+			m.release()
+			if p != path {
+				redirectTo(w, req, p)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redirectTo redirects a request to path keeping the query string.
+// It responds 301 to GET and HEAD requests, and 308 to other requests to keep the method and the body.
+func redirectTo(w http.ResponseWriter, req *http.Request, path string) {
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	// The path is escaped again, so that an escaped character like %3F isn't turned into a query. ex. /a%3Fb
+	u := &url.URL{
+		Path:     path,
+		RawQuery: req.URL.RawQuery,
+	}
+	http.Redirect(w, req, u.String(), code)
+}
+
+// serveNoMatch responds to a request which doesn't match any routes of the method.
+// If the path matches routes of other methods, it responds to an OPTIONS request automatically,
+// and responds 405 to other requests. Both responses have an Allow header.
+// Otherwise it responds 404.
+// The error handlers of the group which has the longest prefix matching the path are used if they are set.
+func (r *Router) serveNoMatch(w http.ResponseWriter, req *http.Request, t *routeTable) {
+	notFound, methodNotAllowed := r.errorHandlers(t, req.URL.Path)
+	allow := r.allowedMethods(t, req.URL.Path, req.Method)
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		if req.Method == http.MethodOptions {
+			if r.DefaultOPTIONSHandler == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			r.DefaultOPTIONSHandler.ServeHTTP(w, req)
+			return
+		}
+		if methodNotAllowed == nil {
+			methodNotAllowedHandler().ServeHTTP(w, req)
+			return
+		}
+		methodNotAllowed.ServeHTTP(w, req)
+		return
+	}
+
+	if notFound == nil {
+		http.NotFoundHandler().ServeHTTP(w, req)
+		return
+	}
+	notFound.ServeHTTP(w, req)
+}
+
+// allowedMethods returns the sorted methods which have a route of t matching path, except for the given method.
+// OPTIONS is always allowed for a path which matches any routes.
+// The path * matches all routes. ex. OPTIONS * HTTP/1.1
+func (r *Router) allowedMethods(t *routeTable, path string, method string) []string {
+	var allow []string
+	for m := range t.trees {
+		if m == method {
+			continue
+		}
+		if path == "*" {
+			allow = append(allow, m)
+			continue
+		}
+		if rm, err := r.search(t, m, path, r.CaseInsensitive); err == nil {
+			rm.release()
+			allow = append(allow, m)
+		}
+	}
+	if len(allow) == 0 {
+		return nil
+	}
+
+	if !hasMethod(allow, http.MethodOptions) {
+		allow = append(allow, http.MethodOptions)
+	}
+	if r.ImplicitHEAD && hasMethod(allow, http.MethodGet) && !hasMethod(allow, http.MethodHead) {
+		allow = append(allow, http.MethodHead)
+	}
+	sort.Strings(allow)
+	return allow
+}
+
+// hasMethod reports whether methods has the method.
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // methodNotAllowedHandler is a default handler when status code is 405.
 func methodNotAllowedHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	})
 }
+
+// headResponseWriter is a response writer for a HEAD request handled by a GET route.
+// It discards the body, and sets the Content-Length header to the length of the discarded body
+// unless the handler sets it.
+type headResponseWriter struct {
+	http.ResponseWriter
+	code   int
+	length int
+}
+
+// WriteHeader holds the status code until the handler returns.
+func (hw *headResponseWriter) WriteHeader(code int) {
+	if hw.code == 0 {
+		hw.code = code
+	}
+}
+
+// Write discards the body and counts its length.
+func (hw *headResponseWriter) Write(b []byte) (int, error) {
+	if hw.code == 0 {
+		hw.code = http.StatusOK
+	}
+	hw.length += len(b)
+	return len(b), nil
+}
+
+// Flush does nothing, so that the status code and the Content-Length header are written after the handler returns.
+// It lets a handler which flushes the response serve a HEAD request as well as a GET request.
+func (hw *headResponseWriter) Flush() {}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (hw *headResponseWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+// flush writes the header with the Content-Length to the original response writer.
+func (hw *headResponseWriter) flush() {
+	if hw.code == 0 {
+		hw.code = http.StatusOK
+	}
+	h := hw.ResponseWriter.Header()
+	if h.Get("Content-Length") == "" && bodyAllowedForStatus(hw.code) {
+		h.Set("Content-Length", strconv.Itoa(hw.length))
+	}
+	hw.ResponseWriter.WriteHeader(hw.code)
+}
+
+// bodyAllowedForStatus reports whether a given response status code permits a body.
+// This function borrowed from net/http package.
+// see https://cs.opensource.google/go/go/+/master:src/net/http/transfer.go
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
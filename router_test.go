@@ -1,18 +1,24 @@
 package goblin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
 func TestNewRouter(t *testing.T) {
-	actual := NewRouter()
-	expected := &Router{
-		tree: map[string]*tree{},
+	actual := NewRouter().table.Load()
+	expected := &routeTable{
+		trees: map[string]*tree{},
 	}
 
 	if !reflect.DeepEqual(actual, expected) {
@@ -86,6 +92,251 @@ func TestRouterMiddleware(t *testing.T) {
 	}
 }
 
+func TestRouteIsolation(t *testing.T) {
+	r := NewRouter()
+
+	// Middlewares and methods of a route without a handler must not leak into the next route.
+	r.Methods(http.MethodPost).Use(first)
+	r.Use(second).Methods(http.MethodGet).Handler(`/second`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/second\n")
+	}))
+	r.Methods(http.MethodGet).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/\n")
+	}))
+
+	cases := []routerTest{
+		{
+			path:   "/second",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "second: before\n/second\nsecond: after\n",
+		},
+		{
+			path:   "/",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "/\n",
+		},
+		{
+			path:   "/",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			body:   "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestRouterConcurrentMiddlewares(t *testing.T) {
+	mark := func(s string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, s)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	g := mark("g")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	// Global middlewares which have spare capacity must not be shared by the middlewares of routes.
+	r.UseGlobal(g, g, g, g, g)
+	r.Methods(http.MethodGet).Use(mark("a")).Handler(`/a`, handler)
+	r.Methods(http.MethodGet).Use(mark("b"), mark("b")).Handler(`/b`, handler)
+
+	expected := map[string]string{
+		"/a": "ggggga",
+		"/b": "gggggbb",
+	}
+	var wg sync.WaitGroup
+	errs := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Body.String() != expected[path] {
+				errs <- fmt.Sprintf("actual: %v expected: %v\n", rec.Body.String(), expected[path])
+			}
+		}([]string{"/a", "/b"}[i%2])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRouterConcurrentRegistration(t *testing.T) {
+	const n = 10
+
+	routers := make([]*Router, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		routers[i] = NewRouter()
+		wg.Add(2)
+		// Build different routers in parallel.
+		go func(r *Router, i int) {
+			defer wg.Done()
+			r.Methods(http.MethodGet).Use(first).Handler(fmt.Sprintf("/routers/%d", i), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "/routers/%d\n", i)
+			}))
+		}(routers[i], i)
+		// Register routes on the same router in parallel.
+		go func(r *Router, i int) {
+			defer wg.Done()
+			r.Methods(http.MethodGet).Handler(fmt.Sprintf("/routers/%d/:id", i), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "/routers/%d/%v\n", i, GetParam(r.Context(), "id"))
+			}))
+		}(routers[i], i)
+	}
+	wg.Wait()
+
+	for i, r := range routers {
+		cases := []routerTest{
+			{
+				path:   fmt.Sprintf("/routers/%d", i),
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   fmt.Sprintf("first: before\n/routers/%d\nfirst: after\n", i),
+			},
+			{
+				path:   fmt.Sprintf("/routers/%d/1", i),
+				method: http.MethodGet,
+				code:   http.StatusOK,
+				body:   fmt.Sprintf("/routers/%d/1\n", i),
+			},
+			{
+				path:   fmt.Sprintf("/routers/%d", (i+1)%n),
+				method: http.MethodGet,
+				code:   http.StatusNotFound,
+				body:   "404 page not found\n",
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name(), func(t *testing.T) {
+				req := httptest.NewRequest(c.method, c.path, nil)
+				rec := httptest.NewRecorder()
+
+				r.ServeHTTP(rec, req)
+
+				if rec.Code != c.code {
+					t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+				}
+
+				recBody, _ := io.ReadAll(rec.Body)
+				body := string(recBody)
+				if body != c.body {
+					t.Errorf("actual: %v expected: %v\n", body, c.body)
+				}
+			})
+		}
+	}
+}
+
+func TestRouterValidate(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/foo/:id[^\d+$]`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/foo/:name`, fooHandler)
+	if err := r.Validate(); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+
+	r.Methods(http.MethodGet, http.MethodPost).Handler(`/foo/:id`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/foo/:name`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/bar/:id[\d+`, fooHandler)
+
+	err := r.Validate()
+	for _, expected := range []error{ErrAmbiguousRoute, ErrDuplicateRoute, ErrInvalidPattern} {
+		if !errors.Is(err, expected) {
+			t.Errorf("actual: %v expected: %v\n", err, expected)
+		}
+	}
+	// POST /foo/:id doesn't conflict.
+	if strings.Contains(err.Error(), "POST") {
+		t.Errorf("actual: %v expected: %v\n", err, "no errors for POST")
+	}
+}
+
+func TestRouterSlashVariant(t *testing.T) {
+	newHandler := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		})
+	}
+
+	cases := []struct {
+		strictSlash bool
+		path        string
+		expected    string
+		expectedErr error
+	}{
+		{strictSlash: false, path: "/foo", expected: "b", expectedErr: ErrDuplicateRoute},
+		{strictSlash: false, path: "/foo/", expected: "b", expectedErr: ErrDuplicateRoute},
+		{strictSlash: true, path: "/foo", expected: "a", expectedErr: nil},
+		{strictSlash: true, path: "/foo/", expected: "b", expectedErr: nil},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%t", c.path, c.strictSlash), func(t *testing.T) {
+			r := NewRouter()
+			r.StrictSlash = c.strictSlash
+			r.Methods(http.MethodGet).Handler(`/foo`, newHandler("a"))
+			r.Methods(http.MethodGet).Handler(`/foo/`, newHandler("b"))
+
+			if err := r.Validate(); !errors.Is(err, c.expectedErr) {
+				t.Errorf("actual: %v expected: %v\n", err, c.expectedErr)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestRouterStrictRegistration(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.StrictRegistration = true
+	r.Methods(http.MethodGet).Handler(`/foo/:id`, fooHandler)
+
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !errors.Is(err, ErrAmbiguousRoute) {
+			t.Errorf("actual: %v expected: %v\n", err, ErrAmbiguousRoute)
+		}
+	}()
+	r.Methods(http.MethodGet).Handler(`/foo/:name`, fooHandler)
+}
+
 func TestRouter(t *testing.T) {
 	r := NewRouter()
 
@@ -116,6 +367,10 @@ func TestRouter(t *testing.T) {
 		name := GetParam(r.Context(), "name")
 		fmt.Fprintf(w, "/foo/%v/%v", id, name)
 	}))
+	r.Methods(http.MethodGet).Handler(`/static/*filepath`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filepath := GetParam(r.Context(), "filepath")
+		fmt.Fprintf(w, "/static/%v", filepath)
+	}))
 	r.Methods(http.MethodPost).Handler(`/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "/")
 	}))
@@ -194,6 +449,18 @@ func TestRouter(t *testing.T) {
 			code:   http.StatusOK,
 			body:   "/foo/123/john",
 		},
+		{
+			path:   "/static/app.js",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "/static/app.js",
+		},
+		{
+			path:   "/static/css/app.css",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   "/static/css/app.css",
+		},
 		{
 			path:   "/",
 			method: http.MethodPost,
@@ -350,6 +617,99 @@ func TestCustomErrorHandler(t *testing.T) {
 	}
 }
 
+func TestMethodNotAllowedAllowHeader(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet, http.MethodPut).Handler(`/users/:id`, fooHandler)
+	r.Methods(http.MethodDelete).Handler(`/users/:id[^\d+$]`, fooHandler)
+	r.Methods(http.MethodPost).Handler(`/posts`, fooHandler)
+
+	custom := NewRouter()
+	custom.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "allow: %v", w.Header().Get("Allow"))
+	})
+	custom.Methods(http.MethodGet, http.MethodPut).Handler(`/users/:id`, fooHandler)
+
+	cases := []struct {
+		router *Router
+		path   string
+		method string
+		code   int
+		allow  string
+		body   string
+	}{
+		{
+			router: r,
+			path:   "/users/1",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "DELETE, GET, OPTIONS, PUT",
+		},
+		{
+			router: r,
+			path:   "/users/john",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, OPTIONS, PUT",
+		},
+		{
+			router: r,
+			path:   "/users/john",
+			method: http.MethodDelete,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, OPTIONS, PUT",
+		},
+		{
+			router: r,
+			path:   "/posts",
+			method: http.MethodPatch,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "OPTIONS, POST",
+		},
+		{
+			router: r,
+			path:   "/comments",
+			method: http.MethodPost,
+			code:   http.StatusNotFound,
+			allow:  "",
+			body:   "404 page not found\n",
+		},
+		{
+			router: custom,
+			path:   "/users/1",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, OPTIONS, PUT",
+			body:   "allow: GET, OPTIONS, PUT",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%s_%d", c.method, c.path, c.code), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			c.router.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			if allow := rec.Header().Get("Allow"); allow != c.allow {
+				t.Errorf("actual: %v expected: %v\n", allow, c.allow)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
 func TestMethodNotAllowedHandler(t *testing.T) {
 	srv := httptest.NewServer(methodNotAllowedHandler())
 	defer srv.Close()
@@ -380,3 +740,646 @@ func TestDefaultOPTIONSHandler(t *testing.T) {
 		t.Errorf("actual: %v expected: %v\n", rec.Code, http.StatusNoContent)
 	}
 }
+
+func TestAutomaticOPTIONS(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet, http.MethodPut).Handler(`/users/:id`, fooHandler)
+	r.Methods(http.MethodPost).Handler(`/users`, fooHandler)
+	r.Methods(http.MethodOptions).Handler(`/explicit`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/explicit")
+	}))
+
+	cors := NewRouter()
+	cors.DefaultOPTIONSHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Methods", w.Header().Get("Allow"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cors.Methods(http.MethodGet, http.MethodPut).Handler(`/users/:id`, fooHandler)
+
+	cases := []struct {
+		router       *Router
+		path         string
+		code         int
+		allow        string
+		allowMethods string
+		body         string
+	}{
+		{
+			router: r,
+			path:   "/users/1",
+			code:   http.StatusNoContent,
+			allow:  "GET, OPTIONS, PUT",
+		},
+		{
+			router: r,
+			path:   "/users",
+			code:   http.StatusNoContent,
+			allow:  "OPTIONS, POST",
+		},
+		{
+			router: r,
+			path:   "*",
+			code:   http.StatusNoContent,
+			allow:  "GET, OPTIONS, POST, PUT",
+		},
+		{
+			router: r,
+			path:   "/explicit",
+			code:   http.StatusOK,
+			allow:  "",
+			body:   "/explicit",
+		},
+		{
+			router: r,
+			path:   "/posts",
+			code:   http.StatusNotFound,
+			allow:  "",
+			body:   "404 page not found\n",
+		},
+		{
+			router:       cors,
+			path:         "/users/1",
+			code:         http.StatusNoContent,
+			allow:        "GET, OPTIONS, PUT",
+			allowMethods: "GET, OPTIONS, PUT",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%d", c.path, c.code), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/", nil)
+			req.URL.Path = c.path
+			rec := httptest.NewRecorder()
+
+			c.router.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			if allow := rec.Header().Get("Allow"); allow != c.allow {
+				t.Errorf("actual: %v expected: %v\n", allow, c.allow)
+			}
+
+			if allowMethods := rec.Header().Get("Access-Control-Allow-Methods"); allowMethods != c.allowMethods {
+				t.Errorf("actual: %v expected: %v\n", allowMethods, c.allowMethods)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestImplicitHEAD(t *testing.T) {
+	newRouter := func(implicitHEAD bool) *Router {
+		r := NewRouter()
+		r.ImplicitHEAD = implicitHEAD
+		r.Methods(http.MethodGet).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/foo")
+		}))
+		r.Methods(http.MethodGet).Handler(`/created`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "/created")
+		}))
+		r.Methods(http.MethodGet).Handler(`/stream`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintf(w, "/stream")
+			w.(http.Flusher).Flush()
+			fmt.Fprintf(w, "/flushed")
+		}))
+		r.Methods(http.MethodGet).Handler(`/explicit`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/explicit")
+		}))
+		r.Methods(http.MethodHead).Handler(`/explicit`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Explicit", "true")
+		}))
+		return r
+	}
+
+	cases := []struct {
+		router        *Router
+		path          string
+		method        string
+		code          int
+		contentLength string
+		allow         string
+		explicit      string
+	}{
+		{
+			router:        newRouter(true),
+			path:          "/foo",
+			method:        http.MethodHead,
+			code:          http.StatusOK,
+			contentLength: "4",
+		},
+		{
+			router:        newRouter(true),
+			path:          "/created",
+			method:        http.MethodHead,
+			code:          http.StatusCreated,
+			contentLength: "100",
+		},
+		{
+			router:        newRouter(true),
+			path:          "/stream",
+			method:        http.MethodHead,
+			code:          http.StatusAccepted,
+			contentLength: "15",
+		},
+		{
+			router:   newRouter(true),
+			path:     "/explicit",
+			method:   http.MethodHead,
+			code:     http.StatusOK,
+			explicit: "true",
+		},
+		{
+			router: newRouter(true),
+			path:   "/foo",
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, HEAD, OPTIONS",
+		},
+		{
+			router: newRouter(true),
+			path:   "/bar",
+			method: http.MethodHead,
+			code:   http.StatusNotFound,
+		},
+		{
+			router: newRouter(false),
+			path:   "/foo",
+			method: http.MethodHead,
+			code:   http.StatusMethodNotAllowed,
+			allow:  "GET, OPTIONS",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%s_%d", c.method, c.path, c.code), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			c.router.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			if c.code != http.StatusNotFound && rec.Body.Len() != 0 {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), "")
+			}
+
+			if contentLength := rec.Header().Get("Content-Length"); contentLength != c.contentLength {
+				t.Errorf("actual: %v expected: %v\n", contentLength, c.contentLength)
+			}
+
+			if allow := rec.Header().Get("Allow"); allow != c.allow {
+				t.Errorf("actual: %v expected: %v\n", allow, c.allow)
+			}
+
+			if explicit := rec.Header().Get("X-Explicit"); explicit != c.explicit {
+				t.Errorf("actual: %v expected: %v\n", explicit, c.explicit)
+			}
+		})
+	}
+}
+
+func TestTrailingSlashAndFixedPath(t *testing.T) {
+	type options struct {
+		strictSlash           bool
+		redirectTrailingSlash bool
+		redirectFixedPath     bool
+	}
+	newRouter := func(o options) *Router {
+		r := NewRouter()
+		r.StrictSlash = o.strictSlash
+		r.RedirectTrailingSlash = o.redirectTrailingSlash
+		r.RedirectFixedPath = o.redirectFixedPath
+		r.Methods(http.MethodGet, http.MethodPost).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/foo")
+		}))
+		r.Methods(http.MethodGet).Handler(`/bar/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/bar/")
+		}))
+		r.Methods(http.MethodGet).Handler(`/baz/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := GetParam(r.Context(), "id")
+			fmt.Fprintf(w, "/baz/%v", id)
+		}))
+		r.Methods(http.MethodGet).Handler(`/static/*filepath`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/static/%v", GetParam(r.Context(), "filepath"))
+		}))
+		return r
+	}
+
+	cases := []struct {
+		options  options
+		path     string
+		method   string
+		code     int
+		body     string
+		location string
+	}{
+		// default
+		{options: options{}, path: "/foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{}, path: "/foo/", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{}, path: "/bar", method: http.MethodGet, code: http.StatusOK, body: "/bar/"},
+		{options: options{}, path: "/bar/", method: http.MethodGet, code: http.StatusOK, body: "/bar/"},
+		{options: options{}, path: "/a/../foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{}, path: "/baz/1/", method: http.MethodGet, code: http.StatusOK, body: "/baz/1"},
+		{options: options{}, path: "/static/", method: http.MethodGet, code: http.StatusOK, body: "/static/"},
+		{options: options{}, path: "/static", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		// StrictSlash
+		{options: options{strictSlash: true}, path: "/foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{strictSlash: true}, path: "/foo/", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{strictSlash: true}, path: "/bar", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{strictSlash: true}, path: "/bar/", method: http.MethodGet, code: http.StatusOK, body: "/bar/"},
+		{options: options{strictSlash: true}, path: "/baz/1/", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{strictSlash: true}, path: "/a/../foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{strictSlash: true}, path: "/static/", method: http.MethodGet, code: http.StatusOK, body: "/static/"},
+		{options: options{strictSlash: true}, path: "/static", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		// RedirectTrailingSlash
+		{options: options{redirectTrailingSlash: true}, path: "/foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{redirectTrailingSlash: true}, path: "/foo/", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/foo"},
+		{options: options{redirectTrailingSlash: true}, path: "/foo/?q=1", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/foo?q=1"},
+		{options: options{redirectTrailingSlash: true}, path: "/foo/", method: http.MethodPost, code: http.StatusPermanentRedirect, location: "/foo"},
+		{options: options{redirectTrailingSlash: true}, path: "/bar", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/bar/"},
+		{options: options{redirectTrailingSlash: true}, path: "/baz/1/", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/baz/1"},
+		{options: options{redirectTrailingSlash: true}, path: "/qux/", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{redirectTrailingSlash: true}, path: "/bar", method: http.MethodPost, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{redirectTrailingSlash: true}, path: "/static", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/static/"},
+		// RedirectFixedPath
+		{options: options{redirectFixedPath: true}, path: "/foo", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{redirectFixedPath: true}, path: "/foo/", method: http.MethodGet, code: http.StatusOK, body: "/foo"},
+		{options: options{redirectFixedPath: true}, path: "/a/../foo", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/foo"},
+		{options: options{redirectFixedPath: true}, path: "//foo", method: http.MethodPost, code: http.StatusPermanentRedirect, location: "/foo"},
+		{options: options{redirectFixedPath: true}, path: "/a/../qux", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		{options: options{redirectFixedPath: true}, path: "/x/../baz/a%3Fb?q=1", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/baz/a%3Fb?q=1"},
+		// RedirectTrailingSlash and RedirectFixedPath
+		{options: options{redirectTrailingSlash: true, redirectFixedPath: true}, path: "/a/../bar/", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/bar/"},
+		{options: options{redirectTrailingSlash: true, redirectFixedPath: true}, path: "/a/../bar", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/bar/"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%+v_%s_%s", c.options, c.method, c.path), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			newRouter(c.options).ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			if location := rec.Header().Get("Location"); location != c.location {
+				t.Errorf("actual: %v expected: %v\n", location, c.location)
+			}
+
+			if c.location == "" && rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	type options struct {
+		caseInsensitive       bool
+		redirectFixedCase     bool
+		redirectTrailingSlash bool
+	}
+	newRouter := func(o options) *Router {
+		r := NewRouter()
+		r.CaseInsensitive = o.caseInsensitive
+		r.RedirectFixedCase = o.redirectFixedCase
+		r.RedirectTrailingSlash = o.redirectTrailingSlash
+		r.Methods(http.MethodGet, http.MethodPost).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := GetParam(r.Context(), "id")
+			fmt.Fprintf(w, "/users/%v", id)
+		}))
+		r.Methods(http.MethodGet).Handler(`/users/me`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/users/me")
+		}))
+		r.Methods(http.MethodGet).Handler(`/Users/Me`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/Users/Me")
+		}))
+		r.Methods(http.MethodGet).Handler(`/files/*path`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := GetParam(r.Context(), "path")
+			fmt.Fprintf(w, "/files/%v", path)
+		}))
+		r.Methods(http.MethodGet).Handler(`/docs/`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/docs/")
+		}))
+		return r
+	}
+
+	cases := []struct {
+		options  options
+		path     string
+		method   string
+		code     int
+		body     string
+		location string
+	}{
+		// default
+		{options: options{}, path: "/users/42", method: http.MethodGet, code: http.StatusOK, body: "/users/42"},
+		{options: options{}, path: "/USERS/42", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		// CaseInsensitive
+		{options: options{caseInsensitive: true}, path: "/Users/42", method: http.MethodGet, code: http.StatusOK, body: "/users/42"},
+		{options: options{caseInsensitive: true}, path: "/USERS/AbC", method: http.MethodGet, code: http.StatusOK, body: "/users/AbC"},
+		{options: options{caseInsensitive: true}, path: "/users/me", method: http.MethodGet, code: http.StatusOK, body: "/users/me"},
+		{options: options{caseInsensitive: true}, path: "/Users/Me", method: http.MethodGet, code: http.StatusOK, body: "/Users/Me"},
+		{options: options{caseInsensitive: true}, path: "/USERS/ME", method: http.MethodGet, code: http.StatusOK, body: "/Users/Me"},
+		{options: options{caseInsensitive: true}, path: "/Files/A/b", method: http.MethodGet, code: http.StatusOK, body: "/files/A/b"},
+		{options: options{caseInsensitive: true}, path: "/USERS/42", method: http.MethodPut, code: http.StatusMethodNotAllowed, body: ""},
+		// RedirectFixedCase
+		{options: options{redirectFixedCase: true}, path: "/users/42", method: http.MethodGet, code: http.StatusOK, body: "/users/42"},
+		{options: options{redirectFixedCase: true}, path: "/USERS/AbC", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/users/AbC"},
+		{options: options{redirectFixedCase: true}, path: "/USERS/42?q=1", method: http.MethodPost, code: http.StatusPermanentRedirect, location: "/users/42?q=1"},
+		{options: options{redirectFixedCase: true}, path: "/FILES/a%3Fb%20c", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/files/a%3Fb%20c"},
+		{options: options{redirectFixedCase: true}, path: "/Users/Me", method: http.MethodGet, code: http.StatusOK, body: "/Users/Me"},
+		{options: options{redirectFixedCase: true}, path: "/USERS/ME", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/Users/Me"},
+		{options: options{redirectFixedCase: true}, path: "/FILES/A/b", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/files/A/b"},
+		{options: options{redirectFixedCase: true}, path: "/DOCS", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/docs/"},
+		{options: options{redirectFixedCase: true}, path: "/POSTS/42", method: http.MethodGet, code: http.StatusNotFound, body: "404 page not found\n"},
+		// RedirectFixedCase and RedirectTrailingSlash
+		{options: options{redirectFixedCase: true, redirectTrailingSlash: true}, path: "/DOCS", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/docs/"},
+		{options: options{redirectFixedCase: true, redirectTrailingSlash: true}, path: "/Users/42/", method: http.MethodGet, code: http.StatusMovedPermanently, location: "/users/42"},
+		// CaseInsensitive takes priority over RedirectFixedCase
+		{options: options{caseInsensitive: true, redirectFixedCase: true}, path: "/USERS/42", method: http.MethodGet, code: http.StatusOK, body: "/users/42"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%+v_%s_%s", c.options, c.method, c.path), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			newRouter(c.options).ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			if location := rec.Header().Get("Location"); location != c.location {
+				t.Errorf("actual: %v expected: %v\n", location, c.location)
+			}
+
+			if c.location == "" && rec.Body.String() != c.body {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.body)
+			}
+		})
+	}
+}
+
+func TestSetPathValue(t *testing.T) {
+	pathValueHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tenant:%v id:%v path:%v", r.PathValue("tenant"), r.PathValue("id"), r.PathValue("path"))
+	})
+	newRouter := func(set bool) *Router {
+		r := NewRouter()
+		r.SetPathValue = set
+		r.Methods(http.MethodGet).Handler(`/users/:id[^\d+$]`, pathValueHandler)
+		r.Methods(http.MethodGet).Handler(`/files/*path`, pathValueHandler)
+
+		child := NewRouter()
+		child.SetPathValue = set
+		child.Methods(http.MethodGet).Handler(`/users/:id`, pathValueHandler)
+		r.Mount(`/tenants/:tenant`, child)
+		return r
+	}
+
+	cases := []struct {
+		router   *Router
+		path     string
+		expected string
+	}{
+		{
+			router:   newRouter(true),
+			path:     "/users/1",
+			expected: "tenant: id:1 path:",
+		},
+		{
+			router:   newRouter(true),
+			path:     "/files/css/main.css",
+			expected: "tenant: id: path:css/main.css",
+		},
+		{
+			router:   newRouter(true),
+			path:     "/tenants/acme/users/1",
+			expected: "tenant:acme id:1 path:",
+		},
+		{
+			router:   newRouter(false),
+			path:     "/users/1",
+			expected: "tenant: id: path:",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%t", c.path, c.router.SetPathValue), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			c.router.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+			if req.PathValue("id") != "" {
+				t.Errorf("actual: %v expected: %v\n", req.PathValue("id"), "")
+			}
+		})
+	}
+}
+
+func TestSetPathValueRequestUnchanged(t *testing.T) {
+	pathValueHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "id:%v lang:%v", r.PathValue("id"), r.PathValue("lang"))
+	})
+	paramsHandler := ParamsHandlerFunc(func(w http.ResponseWriter, r *http.Request, ps Params) {
+		fmt.Fprintf(w, "id:%v lang:%v", r.PathValue("id"), r.PathValue("lang"))
+	})
+	r := NewRouter()
+	r.SetPathValue = true
+	r.Methods(http.MethodGet).Handler(`/users/:id`, pathValueHandler)
+	r.Methods(http.MethodGet).Handler(`/params/:id`, paramsHandler)
+	r.Methods(http.MethodGet).Use(func(next http.Handler) http.Handler {
+		return next
+	}).Handler(`/middlewares/:id`, paramsHandler)
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     "/users/42",
+			expected: "id:42 lang:en",
+		},
+		{
+			path:     "/params/42",
+			expected: "id:42 lang:en",
+		},
+		{
+			path:     "/middlewares/42",
+			expected: "id:42 lang:en",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			// A path value set by the caller, such as http.ServeMux.
+			req.SetPathValue("lang", "en")
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+			if req.PathValue("id") != "" {
+				t.Errorf("actual: %v expected: %v\n", req.PathValue("id"), "")
+			}
+			if req.PathValue("lang") != "en" {
+				t.Errorf("actual: %v expected: %v\n", req.PathValue("lang"), "en")
+			}
+		})
+	}
+}
+
+func TestSubstringPatterns(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	newRouter := func(substring bool) *Router {
+		r := NewRouter()
+		r.SubstringPatterns = substring
+		r.Methods(http.MethodGet).Name("item").Handler(`/items/:id[\d+]`, fooHandler)
+		r.Methods(http.MethodGet).Handler(`/tags/:tag[~^go]`, fooHandler)
+		r.Methods(http.MethodGet).Handler(`/posts/{id:[0-9]+}`, fooHandler)
+		return r
+	}
+
+	cases := []struct {
+		substring bool
+		path      string
+		expected  int
+	}{
+		{substring: false, path: "/items/123", expected: http.StatusOK},
+		{substring: false, path: "/items/abc1def", expected: http.StatusNotFound},
+		{substring: false, path: "/tags/golang", expected: http.StatusOK},
+		{substring: false, path: "/posts/a1", expected: http.StatusNotFound},
+		{substring: true, path: "/items/123", expected: http.StatusOK},
+		{substring: true, path: "/items/abc1def", expected: http.StatusOK},
+		{substring: true, path: "/tags/golang", expected: http.StatusOK},
+		{substring: true, path: "/posts/a1", expected: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%t", c.path, c.substring), func(t *testing.T) {
+			r := newRouter(c.substring)
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.expected)
+			}
+		})
+	}
+
+	if _, err := newRouter(false).URL("item", "id", "a1"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("actual: %v expected: %v\n", err, ErrInvalidParam)
+	}
+	if u, err := newRouter(true).URL("item", "id", "a1"); err != nil || u != "/items/a1" {
+		t.Errorf("actual: %v %v expected: %v\n", u, err, "/items/a1")
+	}
+}
+
+func TestParamsHandlerFunc(t *testing.T) {
+	paramsHandler := ParamsHandlerFunc(func(w http.ResponseWriter, r *http.Request, ps Params) {
+		for _, p := range ps {
+			fmt.Fprintf(w, "%s=%s ", p.Key(), p.Value())
+		}
+		fmt.Fprintf(w, "ctx:%s", GetParam(r.Context(), "id"))
+	})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/users/:id`, paramsHandler)
+	r.Methods(http.MethodGet).Use(first).Handler(`/members/:id`, paramsHandler)
+	child := NewRouter()
+	child.Methods(http.MethodGet).Handler(`/users/:id`, paramsHandler)
+	r.Mount(`/tenants/:tenant`, child)
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/users/1", expected: "id=1 ctx:"},
+		{path: "/members/1", expected: "first: before\nid=1 ctx:1first: after\n"},
+		{path: "/tenants/acme/users/1", expected: "tenant=acme id=1 ctx:"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Body.String() != c.expected {
+				t.Errorf("actual: %v expected: %v\n", rec.Body.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestParamsLifetime(t *testing.T) {
+	var mu sync.Mutex
+	var contexts []context.Context
+
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/direct/:id`, ParamsHandlerFunc(func(w http.ResponseWriter, r *http.Request, ps Params) {
+		runtime.Gosched()
+		fmt.Fprint(w, ps.Get("id"))
+	}))
+	r.Methods(http.MethodGet).Handler(`/context/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The context is kept after the handler returns.
+		mu.Lock()
+		contexts = append(contexts, r.Context())
+		mu.Unlock()
+		fmt.Fprint(w, GetParam(r.Context(), "id"))
+	}))
+
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		for _, prefix := range []string{"/direct/", "/context/"} {
+			wg.Add(1)
+			go func(path string, expected string) {
+				defer wg.Done()
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Body.String() != expected {
+					t.Errorf("actual: %v expected: %v\n", rec.Body.String(), expected)
+				}
+			}(prefix+strconv.Itoa(i), strconv.Itoa(i))
+		}
+	}
+	wg.Wait()
+
+	// The parameters in the kept contexts aren't overwritten by other requests.
+	seen := map[string]bool{}
+	for _, ctx := range contexts {
+		seen[GetParam(ctx, "id")] = true
+	}
+	if len(seen) != n {
+		t.Errorf("actual: %v expected: %v\n", len(seen), n)
+	}
+}
@@ -0,0 +1,101 @@
+package goblin
+
+import (
+	"sort"
+	"strings"
+)
+
+// RouteInfo represents a registered route.
+type RouteInfo struct {
+	// Host is the host pattern of the route. It is empty for a route of the default router.
+	Host string
+	// Name is the name of the route. It is empty if the route doesn't have a name.
+	Name string
+	// Method is the method of the route.
+	Method string
+	// Pattern is the cleaned path of the route as registered. ex. /users/:id[^\d+$], /items/{id:[0-9]+}
+	Pattern string
+	// Params is the names of the parameters and the catch-all parameter in order. ex. [id]
+	Params []string
+	// Constraints is the patterns or the constraints of the parameters which have them, keyed by the name.
+	// ex. map[id:^\d+$], map[id:<int>]
+	Constraints map[string]string
+	// Middlewares is the number of the middlewares of the route, except for the global middlewares.
+	Middlewares int
+	// Mount reports whether the route is a handler mounted with Mount, which handles all methods and the paths under
+	// the pattern. A mounted handler is listed once with an empty Method.
+	Mount bool
+}
+
+// Routes returns the registered routes including the routes of the host routers,
+// sorted by the host, the pattern and the method.
+func (r *Router) Routes() []RouteInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tbl := r.loadTable()
+	var routes []RouteInfo
+	mounts := map[string]bool{}
+	for m, t := range tbl.trees {
+		t.node.walk(func(n *node) {
+			if !n.hasHandler() {
+				return
+			}
+			a := n.action
+			if a.mount == "" {
+				routes = append(routes, newRouteInfo(m, a))
+				return
+			}
+			// A mounted handler is registered for all methods, for the prefix and the paths under it.
+			if !mounts[a.mount] {
+				mounts[a.mount] = true
+				ri := newRouteInfo("", a)
+				ri.Mount = true
+				routes = append(routes, ri)
+			}
+		})
+	}
+	for _, hr := range tbl.hosts {
+		for _, ri := range hr.router.Routes() {
+			ri.Host = hr.pattern
+			routes = append(routes, ri)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// newRouteInfo creates a new RouteInfo from an action of the method.
+func newRouteInfo(method string, a *action) RouteInfo {
+	ri := RouteInfo{
+		Name:        a.name,
+		Method:      method,
+		Pattern:     a.routePattern(),
+		Middlewares: len(a.middlewares),
+	}
+	for _, seg := range strings.Split(a.matchPattern(), "/") {
+		switch getNodeKind(seg) {
+		case nodeKindCatchAll:
+			ri.Params = append(ri.Params, getCatchAllName(seg))
+		case nodeKindRegexp:
+			name := getParamName(seg)
+			ri.Params = append(ri.Params, name)
+			if ri.Constraints == nil {
+				ri.Constraints = map[string]string{}
+			}
+			ri.Constraints[name] = getParamConstraint(seg)
+		case nodeKindParam:
+			ri.Params = append(ri.Params, getParamName(seg))
+		}
+	}
+	return ri
+}
@@ -0,0 +1,103 @@
+package goblin
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.UseGlobal(global)
+	r.Methods(http.MethodGet).Handler(`/`, fooHandler)
+	r.Methods(http.MethodGet, http.MethodPost).Use(first).Name("users").Handler(`/users`, fooHandler)
+	r.Methods(http.MethodGet).Use(first, second).Handler(`/users/:id[^\d+$]/posts/:slug`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/users/:name`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/files/*path`, fooHandler)
+	r.Methods(http.MethodGet).Handler(`/orders/:id<uuid>`, fooHandler)
+	r.Handle(`GET /items/{id:[0-9]+}`, fooHandler)
+	r.Host("{tenant}.example.com").Methods(http.MethodGet).Handler(`/foo`, fooHandler)
+	r.Group(`/tenants/{tenant}`, func(g *Group) {
+		g.UseGroup(first)
+		g.Mount(`/admin`, fooHandler)
+		g.Methods(http.MethodGet).Handler(`/users/:id`, fooHandler)
+	})
+	r.Mount(`/shops/{shop}`, fooHandler)
+
+	expected := []RouteInfo{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/",
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/files/*path",
+			Params:  []string{"path"},
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/items/{id:[0-9]+}",
+			Params:      []string{"id"},
+			Constraints: map[string]string{"id": "^(?:[0-9]+)$"},
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/orders/:id<uuid>",
+			Params:      []string{"id"},
+			Constraints: map[string]string{"id": "<uuid>"},
+		},
+		{
+			Pattern: "/shops/{shop}",
+			Params:  []string{"shop"},
+			Mount:   true,
+		},
+		{
+			Pattern:     "/tenants/{tenant}/admin",
+			Params:      []string{"tenant"},
+			Middlewares: 1,
+			Mount:       true,
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/tenants/{tenant}/users/:id",
+			Params:      []string{"tenant", "id"},
+			Middlewares: 1,
+		},
+		{
+			Name:        "users",
+			Method:      http.MethodGet,
+			Pattern:     "/users",
+			Middlewares: 1,
+		},
+		{
+			Name:        "users",
+			Method:      http.MethodPost,
+			Pattern:     "/users",
+			Middlewares: 1,
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     `/users/:id[^\d+$]/posts/:slug`,
+			Params:      []string{"id", "slug"},
+			Constraints: map[string]string{"id": `^\d+$`},
+			Middlewares: 2,
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/users/:name",
+			Params:  []string{"name"},
+		},
+		{
+			Host:    "{tenant}.example.com",
+			Method:  http.MethodGet,
+			Pattern: "/foo",
+		},
+	}
+
+	actual := r.Routes()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual: %v expected: %v\n", actual, expected)
+	}
+}
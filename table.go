@@ -0,0 +1,96 @@
+package goblin
+
+// routeTable is a snapshot of the routes of a router.
+// A new table is published as a whole whenever routes are changed, and a published table is never modified,
+// so that requests are served without locks and never block on registrations.
+type routeTable struct {
+	trees             map[string]*tree
+	globalMiddlewares middlewares
+	groups            []*Group
+	hosts             []*hostRouter
+	constraints       constraints // registered constraints, which are replaced as a whole
+}
+
+// emptyTable is the table of a router which isn't created by NewRouter.
+var emptyTable = &routeTable{}
+
+// loadTable returns the current table of the router.
+func (r *Router) loadTable() *routeTable {
+	if t := r.table.Load(); t != nil {
+		return t
+	}
+	return emptyTable
+}
+
+// updateTable calls fn with a copy of the current table, and publishes the copy.
+// fn must clone a tree before modifying it. It must be called with r.mu held.
+func (r *Router) updateTable(fn func(t *routeTable)) {
+	old := r.loadTable()
+	t := &routeTable{
+		trees:             make(map[string]*tree, len(old.trees)),
+		globalMiddlewares: old.globalMiddlewares,
+		// Appending to the slices always copies them.
+		groups:      old.groups[:len(old.groups):len(old.groups)],
+		hosts:       old.hosts[:len(old.hosts):len(old.hosts)],
+		constraints: old.constraints,
+	}
+	for m, tr := range old.trees {
+		t.trees[m] = tr
+	}
+	fn(t)
+	r.table.Store(t)
+}
+
+// Remove removes the route of the method and the path. The path must be the same as the registered one,
+// including the prefix of the group. ex. /users/:id[^\d+$]
+// Requests being served keep using the routes at the time they started.
+// The names of the route are removed when no method has the route.
+// It reports whether the route was registered.
+func (r *Router) Remove(method string, path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.loadTable().trees[method]; !ok {
+		return false
+	}
+	var removed bool
+	r.updateTable(func(t *routeTable) {
+		tr := t.trees[method].clone()
+		removed = tr.Remove(path)
+		t.trees[method] = tr
+	})
+	if removed {
+		r.removeNames(path)
+	}
+	return removed
+}
+
+// removeNames removes the names of the route of path if no method has the route.
+// It must be called with r.mu held.
+func (r *Router) removeNames(path string) {
+	if p, err := parsePattern(path); err == nil {
+		path = p
+	}
+	pattern := canonicalPath(path)
+	for _, t := range r.loadTable().trees {
+		if t.hasPattern(pattern) {
+			return
+		}
+	}
+	for name, nr := range r.names {
+		if canonicalPath(nr.pattern) == pattern {
+			delete(r.names, name)
+		}
+	}
+}
+
+// hasPattern reports whether t has the route of the cleaned pattern.
+func (t *tree) hasPattern(pattern string) bool {
+	var found bool
+	t.node.walk(func(n *node) {
+		if n.hasHandler() && n.action.pattern == pattern {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,165 @@
+package goblin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRouterRemove(t *testing.T) {
+	r := NewRouter()
+	r.Methods(http.MethodGet, http.MethodPost).Handler(`/users/:id`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/users/%v", GetParam(r.Context(), "id"))
+	}))
+	r.Group(`/api`, func(g *Group) {
+		g.Methods(http.MethodGet).Handler(`/users`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "/api/users")
+		}))
+	})
+
+	if r.Remove(http.MethodPut, `/users/:id`) {
+		t.Errorf("actual: %v expected: %v\n", true, false)
+	}
+	if !r.Remove(http.MethodGet, `/users/:id`) {
+		t.Errorf("actual: %v expected: %v\n", false, true)
+	}
+	if !r.Remove(http.MethodGet, `/api/users`) {
+		t.Errorf("actual: %v expected: %v\n", false, true)
+	}
+
+	cases := []routerTest{
+		{
+			path:   "/users/1",
+			method: http.MethodGet,
+			code:   http.StatusMethodNotAllowed,
+			body:   "",
+		},
+		{
+			path:   "/users/1",
+			method: http.MethodPost,
+			code:   http.StatusOK,
+			body:   "/users/1",
+		},
+		{
+			path:   "/api/users",
+			method: http.MethodGet,
+			code:   http.StatusNotFound,
+			body:   "404 page not found\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("actual: %v expected: %v\n", rec.Code, c.code)
+			}
+
+			recBody, _ := io.ReadAll(rec.Body)
+			body := string(recBody)
+			if body != c.body {
+				t.Errorf("actual: %v expected: %v\n", body, c.body)
+			}
+		})
+	}
+}
+
+func TestRouterRemoveName(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet, http.MethodPost).Name("u").Handler(`/users/{id:[0-9]+}`, fooHandler)
+
+	// The name is kept while POST has the route.
+	r.Remove(http.MethodGet, `/users/{id:[0-9]+}`)
+	if u, err := r.URL("u", "id", "5"); err != nil || u != "/users/5" {
+		t.Errorf("actual: %v %v expected: %v\n", u, err, "/users/5")
+	}
+
+	r.Remove(http.MethodPost, `/users/{id:[0-9]+}`)
+	if _, err := r.URL("u", "id", "5"); !errors.Is(err, ErrUnknownRoute) {
+		t.Errorf("actual: %v expected: %v\n", err, ErrUnknownRoute)
+	}
+}
+
+func TestRouterReplace(t *testing.T) {
+	r := NewRouter()
+	r.StrictRegistration = true
+	r.Methods(http.MethodGet).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "v1")
+	}))
+	r.Methods(http.MethodGet).Use(first).Replace(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "v2\n")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	expected := "first: before\nv2\nfirst: after\n"
+	if rec.Body.String() != expected {
+		t.Errorf("actual: %v expected: %v\n", rec.Body.String(), expected)
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("actual: %v expected: %v\n", err, nil)
+	}
+}
+
+func TestRouterServeWhileRegistering(t *testing.T) {
+	r := NewRouter()
+	r.Methods(http.MethodGet).Handler(`/static`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "/static")
+	}))
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			path := fmt.Sprintf("/dynamic/%d", i)
+			r.UseGlobal()
+			r.Methods(http.MethodGet).Handler(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r.Group(path, nil)
+			r.Host(fmt.Sprintf("%d.example.com", i))
+			r.Remove(http.MethodGet, path)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/static", nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || rec.Body.String() != "/static" {
+				t.Errorf("actual: %v %v expected: %v %v\n", rec.Code, rec.Body.String(), http.StatusOK, "/static")
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestZeroRouter(t *testing.T) {
+	var r Router
+	r.Methods(http.MethodGet).Handler(`/foo`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/bar", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("actual: %v expected: %v\n", rec.Code, http.StatusNotFound)
+	}
+}
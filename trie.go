@@ -1,57 +1,123 @@
 package goblin
 
 import (
+	"fmt"
 	"net/http"
 	"path"
-	"regexp"
 	"strings"
 	"sync"
 )
 
-// tree is a trie tree.
+// tree is a radix tree.
+// A tree can be cloned cheaply. A clone shares nodes with the original tree,
+// and Insert and Remove copy shared nodes before modifying them, so that the original tree is never modified.
 type tree struct {
 	node       *node
 	paramsPool sync.Pool
 	maxParams  int
+	gen        uint64 // generation of nodes which belong to the tree
 }
 
 // node is a node of tree.
+// A static node holds a compressed prefix of paths, and a parameter node holds a whole path segment.
 type node struct {
 	label    string
-	action   *action // key is method
-	children []*node // key is label of next nodes
+	kind     nodeKind
+	action   *action
+	indices  string            // first bytes of labels of static children
+	children []*node           // static children, key is indices
+	params   []*node           // parameter children, ordered by kind
+	catchAll *node             // catch-all child
+	name     string            // name of a parameter or a catch-all parameter
+	match    func(string) bool // compiled pattern or constraint of a parameter which has it
+	gen      uint64            // generation of the tree which the node belongs to
+}
+
+// nodeKind is a kind of node.
+// The order of kinds is the matching priority among sibling nodes.
+type nodeKind uint8
+
+const (
+	// nodeKindStatic is a node for a static label. ex. foo
+	nodeKindStatic nodeKind = iota
+	// nodeKindRegexp is a node for a parameter with a pattern or a constraint. ex. :id[^\d+$], :id<int>
+	nodeKindRegexp
+	// nodeKindParam is a node for a parameter. ex. :id
+	nodeKindParam
+	// nodeKindCatchAll is a node for a catch-all parameter. ex. *filepath
+	nodeKindCatchAll
+)
+
+// getNodeKind gets a kind of node from a label.
+func getNodeKind(label string) nodeKind {
+	switch {
+	case strings.HasPrefix(label, paramDelimiter):
+		if getPattern(label) != "" || getConstraintName(label) != "" {
+			return nodeKindRegexp
+		}
+		return nodeKindParam
+	case strings.HasPrefix(label, catchAllDelimiter):
+		return nodeKindCatchAll
+	default:
+		return nodeKindStatic
+	}
 }
 
 // action is an action.
 type action struct {
 	middlewares middlewares
 	handler     http.Handler
+	pattern     string // cleaned path of the route. ex. /foo/:id
+	written     string // pattern as registered if it has parameters enclosed in braces. ex. /foo/{id}
+	name        string // name of the route. It is empty if the route doesn't have a name.
+	mount       string // cleaned prefix of a handler mounted with Mount. It is empty for other routes.
+}
+
+// routePattern returns the pattern of the route which is shown to users.
+// It is the prefix for a mounted handler instead of the internal catch-all route. ex. /admin
+// It is the pattern as registered for a route with parameters enclosed in braces. ex. /items/{id:[0-9]+}
+func (a *action) routePattern() string {
+	if a.written != "" {
+		return a.written
+	}
+	return a.matchPattern()
+}
+
+// matchPattern returns the pattern of the route which has parameters of goblin. ex. /items/:id
+// It is the prefix for a mounted handler.
+func (a *action) matchPattern() string {
+	if a.mount != "" {
+		return a.mount
+	}
+	return a.pattern
 }
 
 const (
 	paramDelimiter    string = ":"
+	catchAllDelimiter string = "*"
 	leftPtnDelimiter  string = "["
 	rightPtnDelimiter string = "]"
+	substringPtnMark  string = "~" // a pattern which starts with it matches a substring of a segment. ex. :id[~\d+]
 	ptnWildcard       string = "(.+)"
 )
 
-// newTree creates a new trie tree.
+// newTree creates a new radix tree.
 func newTree() *tree {
 	return &tree{
 		node: &node{
-			label:    "/",
-			action:   &action{},
-			children: []*node{},
+			label:  "/",
+			action: &action{},
 		},
 	}
 }
 
-// Param is a parameter.
+// Param is a parameter of a path. Use NewParam to create it outside the package.
 type Param struct {
 	key   string
 	value string
 }
 
+// Params is parameters of a path in the order of the path.
 type Params []Param
 
 // getParams gets parameters.
@@ -68,215 +134,626 @@ func (t *tree) putParams(ps *Params) {
 	}
 }
 
-func (n *node) getChild(label string) *node {
-	for i := 0; i < len(n.children); i++ {
-		if n.children[i].label == label {
+// clone returns a copy of t which shares nodes with t.
+func (t *tree) clone() *tree {
+	nt := &tree{
+		node:      t.node,
+		maxParams: t.maxParams,
+		gen:       t.gen + 1,
+	}
+	nt.setParamsPool()
+	return nt
+}
+
+// own returns n if n belongs to t, otherwise a copy of n which belongs to t.
+// The copy shares descendants with n.
+func (t *tree) own(n *node) *node {
+	if n.gen == t.gen {
+		return n
+	}
+	c := *n
+	c.gen = t.gen
+	c.children = append([]*node(nil), n.children...)
+	c.params = append([]*node(nil), n.params...)
+	return &c
+}
+
+// setParamsPool sets a function which creates parameters to the pool if the tree has parameters.
+func (t *tree) setParamsPool() {
+	if t.paramsPool.New == nil && t.maxParams > 0 {
+		t.paramsPool.New = func() interface{} {
+			p := make(Params, 0, t.maxParams)
+			return &p
+		}
+	}
+}
+
+// hasHandler reports whether n has a handler.
+func (n *node) hasHandler() bool {
+	return n.action != nil && n.action.handler != nil
+}
+
+// getStaticChild gets a static child whose label starts with c.
+func (n *node) getStaticChild(c byte) *node {
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
 			return n.children[i]
 		}
 	}
 	return nil
 }
 
-// Insert inserts a route definition to tree.
-func (t *tree) Insert(path string, handler http.Handler, mws middlewares) {
-	path = cleanPath(path)
-	curNode := t.node
+// insertStatic inserts a static label below n, splitting a child if necessary.
+// n must belong to t. It returns the node for the end of the label.
+func (t *tree) insertStatic(n *node, label string) *node {
+	if label == "" {
+		return n
+	}
 
-	if path == "/" {
-		curNode.label = path
-		curNode.action = &action{
-			middlewares: mws,
-			handler:     handler,
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != label[0] {
+			continue
+		}
+		c := t.own(n.children[i])
+		n.children[i] = c
+		l := longestCommonPrefix(c.label, label)
+		if l < len(c.label) {
+			// Split the child. ex. foo → fo, o
+			prefix := &node{
+				label:    c.label[:l],
+				kind:     nodeKindStatic,
+				indices:  c.label[l : l+1],
+				children: []*node{c},
+				gen:      t.gen,
+			}
+			c.label = c.label[l:]
+			n.children[i] = prefix
+			c = prefix
 		}
-		return
+		return t.insertStatic(c, label[l:])
 	}
 
-	path = removeTrailingSlash(path)
+	child := &node{
+		label: label,
+		kind:  nodeKindStatic,
+		gen:   t.gen,
+	}
+	n.indices += label[:1]
+	n.children = append(n.children, child)
+	return child
+}
 
-	cnt := strings.Count(path, "/")
-	var l string
+// insertParam inserts a parameter or a catch-all label below n. match is the compiled pattern or constraint of the label.
+// n must belong to t. It returns the node for the label, and ErrAmbiguousRoute if the label conflicts with a sibling.
+// Even if the label conflicts, the node is inserted.
+func (t *tree) insertParam(n *node, label string, match func(string) bool) (*node, error) {
+	kind := getNodeKind(label)
+	if kind == nodeKindCatchAll {
+		var err error
+		if n.catchAll != nil && n.catchAll.label != label {
+			err = fmt.Errorf("%w: %s conflicts with %s", ErrAmbiguousRoute, label, n.catchAll.label)
+		}
+		if n.catchAll == nil || n.catchAll.label != label {
+			n.catchAll = &node{
+				label: label,
+				kind:  kind,
+				name:  getCatchAllName(label),
+				gen:   t.gen,
+			}
+		}
+		n.catchAll = t.own(n.catchAll)
+		return n.catchAll, err
+	}
 
-	for i := 0; i < cnt; i++ {
-		// Delete the / at head of path. ex. /foo/bar → foo/bar
-		if path[:1] == "/" {
-			path = path[1:]
+	var err error
+	for i, c := range n.params {
+		if c.label == label {
+			n.params[i] = t.own(c)
+			n.params[i].match = match
+			return n.params[i], nil
+		}
+		// Params which match the same segments with different names are ambiguous.
+		// ex. :id and :name, :id[^\d+$] and :num[^\d+$], :id<int> and :num<int>
+		if c.kind == kind && getParamConstraint(c.label) == getParamConstraint(label) && err == nil {
+			err = fmt.Errorf("%w: %s conflicts with %s", ErrAmbiguousRoute, label, c.label)
 		}
+	}
+
+	child := &node{
+		label: label,
+		kind:  kind,
+		name:  getParamName(label),
+		match: match,
+		gen:   t.gen,
+	}
+	// Keep params ordered by kind.
+	// Params of the same kind keep the order of insertion.
+	i := len(n.params)
+	for i > 0 && n.params[i-1].kind > child.kind {
+		i--
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child, err
+}
 
-		idx := strings.Index(path, "/")
-		l = path
-		if idx > 0 {
-			// ex. foo/bar/baz → foo
+// Insert inserts a route definition to tree.
+// path can have parameters enclosed in braces. ex. /items/{id}
+// It returns ErrInvalidPattern without inserting the route if path is malformed.
+// It returns ErrDuplicateRoute or ErrAmbiguousRoute if the route conflicts with registered routes,
+// but the route is inserted anyway. If there is already registered data, it is overwritten.
+func (t *tree) Insert(path string, handler http.Handler, mws middlewares) error {
+	return t.insert(path, &action{
+		middlewares: mws,
+		handler:     handler,
+	}, nil, false)
+}
+
+// insert inserts a route definition with a to tree like Insert. The pattern of a is set to the cleaned path.
+// Patterns of parameters are compiled, and constraints of parameters are looked up from cs.
+// If substring is true, patterns match substrings of segments. See compileParam.
+func (t *tree) insert(path string, a *action, cs constraints, substring bool) error {
+	path, err := parsePattern(path)
+	if err != nil {
+		return err
+	}
+	if err := validatePath(path); err != nil {
+		return err
+	}
+	matchers, err := compileParams(path, cs, substring)
+	if err != nil {
+		return err
+	}
+
+	path = canonicalPath(path)
+	pattern := path
+	t.node = t.own(t.node)
+	curNode := t.node
+
+	var conflict error
+	// Delete the / at head of path, which is the label of root. ex. /foo/:id → foo/:id
+	path = path[1:]
+	cnt := 0
+	for path != "" {
+		i := indexParam(path)
+		if i != 0 {
+			if i < 0 {
+				i = len(path)
+			}
+			// ex. foo/:id/bar → foo/
+			curNode = t.insertStatic(curNode, path[:i])
+			path = path[i:]
+			continue
+		}
+
+		// ex. :id/bar → :id
+		l := path
+		if idx := strings.Index(path, "/"); idx > 0 {
 			l = path[:idx]
 		}
+		var err error
+		curNode, err = t.insertParam(curNode, l, matchers[cnt])
+		if err != nil && conflict == nil {
+			conflict = err
+		}
+		path = path[len(l):]
+		cnt++
+	}
+
+	if curNode.hasHandler() && conflict == nil {
+		conflict = ErrDuplicateRoute
+	}
+	a.pattern = pattern
+	curNode.action = a
+
+	if t.maxParams < cnt {
+		t.maxParams = cnt
+	}
+	t.setParamsPool()
+
+	return conflict
+}
+
+// Remove removes the route of path from tree. path must be the same path as the registered one.
+// Nodes which no longer lead to any routes are removed as well.
+// It reports whether the route was registered.
+func (t *tree) Remove(path string) bool {
+	path, err := parsePattern(path)
+	if err != nil || validatePath(path) != nil {
+		return false
+	}
+	path = canonicalPath(path)
 
-		nextNode := curNode.getChild(l)
-		if nextNode != nil {
-			curNode = nextNode
-			if idx > 0 {
-				l = path[:idx]
-				// foo/bar/baz → /bar/baz
-				path = path[idx:]
+	root := t.own(t.node)
+	if !t.remove(root, path[1:]) {
+		return false
+	}
+	t.node = root
+	return true
+}
+
+// remove removes the route of path below n. n must belong to t.
+// path is the rest of the registered path after the label of n.
+func (t *tree) remove(n *node, path string) bool {
+	if path == "" {
+		if !n.hasHandler() {
+			return false
+		}
+		n.action = nil
+		return true
+	}
+
+	if indexParam(path) != 0 {
+		for i, c := range n.children {
+			if !strings.HasPrefix(path, c.label) {
+				continue
 			}
-		} else {
-			// Create a new node.
-			child := &node{
-				label:    l,
-				action:   &action{},
-				children: []*node{},
+			c = t.own(c)
+			if !t.remove(c, path[len(c.label):]) {
+				return false
 			}
-			curNode.children = append(curNode.children, child)
-			curNode = child
-			if idx > 0 {
-				l = path[:idx]
-				// foo/bar/baz → /bar/baz
-				path = path[idx:]
+			if c.isEmpty() {
+				n.indices = n.indices[:i] + n.indices[i+1:]
+				n.children = append(n.children[:i], n.children[i+1:]...)
+			} else {
+				n.children[i] = c
 			}
+			return true
+		}
+		return false
+	}
+
+	// ex. :id/bar → :id
+	l := path
+	if idx := strings.Index(path, "/"); idx > 0 {
+		l = path[:idx]
+	}
+	if n.catchAll != nil && n.catchAll.label == l {
+		c := t.own(n.catchAll)
+		if !t.remove(c, path[len(l):]) {
+			return false
+		}
+		n.catchAll = c
+		if c.isEmpty() {
+			n.catchAll = nil
+		}
+		return true
+	}
+	for i, c := range n.params {
+		if c.label != l {
+			continue
+		}
+		c = t.own(c)
+		if !t.remove(c, path[len(l):]) {
+			return false
+		}
+		if c.isEmpty() {
+			n.params = append(n.params[:i], n.params[i+1:]...)
+		} else {
+			n.params[i] = c
+		}
+		return true
+	}
+	return false
+}
+
+// isEmpty reports whether n doesn't have a handler nor any children.
+func (n *node) isEmpty() bool {
+	return !n.hasHandler() && len(n.children) == 0 && len(n.params) == 0 && n.catchAll == nil
+}
+
+// validatePath validates parameters and catch-all parameters in path.
+func validatePath(path string) error {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
 		}
-		// last loop.
-		// If there is already registered data, overwrite it.
-		if i == cnt-1 {
-			curNode.label = l
-			curNode.action = &action{
-				middlewares: mws,
-				handler:     handler,
+		switch getNodeKind(seg) {
+		case nodeKindCatchAll:
+			if getCatchAllName(seg) == "" {
+				return fmt.Errorf("%w: %s doesn't have a name", ErrInvalidPattern, seg)
+			}
+			for _, rest := range segments[i+1:] {
+				if rest != "" {
+					return fmt.Errorf("%w: %s must be at the end of path", ErrInvalidPattern, seg)
+				}
+			}
+		case nodeKindRegexp, nodeKindParam:
+			if err := validateParam(seg); err != nil {
+				return err
 			}
-			break
 		}
 	}
-	if t.maxParams < cnt {
-		t.maxParams = cnt
+	return nil
+}
+
+// validateParam validates the syntax of a parameter label. A pattern is compiled by compileParam.
+// ex.
+// :id         → valid
+// :id[^\d+$]  → valid
+// :id[~\d+]   → valid
+// :id<int>    → valid
+// :id[~]      → invalid
+// :id[^\d+$   → invalid
+// :id[^\d+$]x → invalid
+// :id<int     → invalid
+func validateParam(label string) error {
+	pn := getParamName(label)
+	if pn == "" || strings.ContainsAny(pn, rightPtnDelimiter+rightConstraintDelimiter) {
+		return fmt.Errorf("%w: %s doesn't have a valid name", ErrInvalidPattern, label)
 	}
-	if t.paramsPool.New == nil && t.maxParams > 0 {
-		t.paramsPool.New = func() interface{} {
-			p := make(Params, 0, t.maxParams)
-			return &p
+	if rest := label[len(paramDelimiter)+len(pn):]; strings.HasPrefix(rest, leftConstraintDelimiter) {
+		if !strings.HasSuffix(rest, rightConstraintDelimiter) {
+			return fmt.Errorf("%w: %s doesn't have a closing %s", ErrInvalidPattern, label, rightConstraintDelimiter)
 		}
+		if name := getConstraintName(label); name == "" || strings.ContainsAny(name, "<>[]") {
+			return fmt.Errorf("%w: %s doesn't have a valid constraint", ErrInvalidPattern, label)
+		}
+		return nil
+	}
+	if !strings.Contains(label, leftPtnDelimiter) {
+		return nil
+	}
+	if !strings.HasSuffix(label, rightPtnDelimiter) {
+		return fmt.Errorf("%w: %s doesn't have a closing %s", ErrInvalidPattern, label, rightPtnDelimiter)
+	}
+	ptn := strings.TrimPrefix(getPattern(label), substringPtnMark)
+	if ptn == "" {
+		return fmt.Errorf("%w: %s has an empty pattern", ErrInvalidPattern, label)
 	}
+	return nil
 }
 
-// regCache represents the cache for a regular expression.
-type regCache struct {
-	s sync.Map
+// indexParam returns the index of the first segment which is a parameter or a catch-all in path.
+// path must start at the head of a segment. It returns -1 if path doesn't have any parameters.
+// ex.
+// :id/foo      → 0
+// foo/:id      → 4
+// foo/*path    → 4
+// foo/bar      → -1
+func indexParam(path string) int {
+	for i := 0; i < len(path); i++ {
+		if i > 0 && path[i-1] != '/' {
+			continue
+		}
+		if path[i] == paramDelimiter[0] || path[i] == catchAllDelimiter[0] {
+			return i
+		}
+	}
+	return -1
 }
 
-// getReg gets a compiled regexp from cache or create it.
-func (rc *regCache) getReg(ptn string) (*regexp.Regexp, error) {
-	v, ok := rc.s.Load(ptn)
-	if ok {
-		reg, _ := v.(*regexp.Regexp)
-		return reg, nil
+// longestCommonPrefix returns the length of the longest common prefix of a and b.
+func longestCommonPrefix(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
 	}
-	reg, err := regexp.Compile(ptn)
-	if err != nil {
-		return nil, err
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
 	}
-	rc.s.Store(ptn, reg)
-	return reg, nil
+	return i
 }
 
-var regC = &regCache{}
-
 // Search searches a path from a tree.
+// The path is cleaned, and a trailing slash of the path is ignored. ex. /foo/ and /foo match both /foo and /foo/
+// When several sibling nodes can match a path, they are tried in the following order,
+// backtracking to the next candidate when no route is found below the matched node.
+//  1. static label. ex. foo
+//  2. parameter with a pattern or a constraint, in the order of insertion. ex. :id[^\d+$], :id<int>
+//  3. parameter. ex. :id
+//  4. catch-all parameter. ex. *filepath
 func (t *tree) Search(path string) (*action, Params, error) {
 	path = cleanPath(path)
-	curNode := t.node
+	opts := searchTSR
+	if path != "/" && strings.HasSuffix(path, "/") {
+		path = removeTrailingSlash(path)
+		opts |= searchSlash
+	}
+	return t.search(path, opts)
+}
 
-	path = removeTrailingSlash(path)
+// searchOption is an option for searching a path.
+type searchOption uint8
 
-	cnt := strings.Count(path, "/")
-	var l string
-	var params Params
+const (
+	// searchTSR makes a route which has a trailing slash match the path without it.
+	searchTSR searchOption = 1 << iota
+	// searchFold makes static labels match case-insensitively. Only ASCII letters are folded.
+	searchFold
+	// searchSlash tells that a trailing slash was removed from the path, so that a catch-all parameter matches
+	// the empty rest of the path. ex. /static/ → /static/*filepath, but not /static
+	searchSlash
+)
 
-	for i := 0; i < cnt; i++ {
-		// Delete the / at head of path. ex. /foo/bar → foo/bar
-		if path[:1] == "/" {
-			path = path[1:]
-		}
+// search searches a cleaned path from a tree with opts. The parameters are a copy, which the caller owns.
+func (t *tree) search(path string, opts searchOption) (*action, Params, error) {
+	a, ps, err := t.find(path, opts)
+	if ps == nil {
+		return a, nil, err
+	}
+	params := append(make(Params, 0, len(*ps)), *ps...)
+	t.putParams(ps)
+	return a, params, err
+}
 
-		idx := strings.Index(path, "/")
-		// ex. foo → foo
-		l = path
-		if idx > 0 {
-			// ex. foo/bar/baz → foo
-			l = path[:idx]
-		}
+// find searches a cleaned path from a tree with opts like search, but returns the pooled parameters.
+// The caller must put them back with putParams after use. They are nil if the path has no parameters.
+func (t *tree) find(path string, opts searchOption) (*action, *Params, error) {
+	var ps *Params
+	if t.paramsPool.New != nil {
+		ps = t.getParams()
+	}
 
-		cc := curNode.children
+	// Delete the / at head of path, which is the label of root. ex. /foo/bar → foo/bar
+	n := t.node.search(path[1:], ps, opts)
+	if n == nil {
+		t.putParams(ps)
+		// no matching path was found.
+		return nil, nil, ErrNotFound
+	}
 
-		// leaf node
-		if len(cc) == 0 {
-			if curNode.label != l {
-				// no matching path was found.
-				return nil, nil, ErrNotFound
-			}
+	if ps == nil || len(*ps) == 0 {
+		t.putParams(ps)
+		return n.action, nil, nil
+	}
+	return n.action, ps, nil
+}
+
+// search searches a node which has a handler for path below n.
+// path is the rest of the request path after the label of n.
+func (n *node) search(path string, ps *Params, opts searchOption) *node {
+	if path == "" {
+		// The label of a node which has a catch-all parameter ends with a slash, which the path had.
+		if m := n.matchEnd(ps, true); m != nil {
+			return m
 		}
+		// A route with a trailing slash. ex. foo → foo/
+		if c := n.getStaticChild('/'); opts&searchTSR != 0 && c != nil && c.label == "/" {
+			return c.matchEnd(ps, opts&searchSlash != 0)
+		}
+		// no matching handler and middlewares was found.
+		return nil
+	}
 
-		nextNode := curNode.getChild(l)
-		if nextNode != nil {
-			curNode = nextNode
-			if idx > 0 {
-				// foo/bar/baz → /bar/baz
-				path = path[idx:]
+	if c := n.getStaticChild(path[0]); c != nil {
+		if m := c.searchStatic(path, ps, opts); m != nil {
+			return m
+		}
+	}
+	// A label in the other case is tried after a label in the same case. ex. Foo → foo
+	if b := toggleCase(path[0]); opts&searchFold != 0 && b != path[0] {
+		if c := n.getStaticChild(b); c != nil {
+			if m := c.searchStatic(path, ps, opts); m != nil {
+				return m
 			}
-			continue
 		}
+	}
 
-		isParamMatch := false
-		// parameter matching
-		for _, c := range cc {
-			if c.label[0:1] == paramDelimiter {
-				ptn := getPattern(c.label)
-				if ptn != "" {
-					reg, err := regC.getReg(ptn)
-					if err != nil {
-						return nil, nil, ErrNotFound
-					}
-					if !reg.Match([]byte(l)) {
-						return nil, nil, ErrNotFound
-					}
-				}
-
-				pn := getParamName(c.label)
-
-				if params == nil {
-					ps := t.getParams()
-					params = (*ps)[0:1]
-					params[0] = Param{
-						key:   pn,
-						value: l,
-					}
-					t.putParams(ps)
-				} else {
-					params = append(params, Param{
-						key:   pn,
-						value: l,
-					})
+	if len(n.params) > 0 {
+		// ex. foo/bar → foo
+		l := path
+		if idx := strings.Index(path, "/"); idx >= 0 {
+			l = path[:idx]
+		}
+		if l != "" {
+			for _, c := range n.params {
+				if c.match != nil && !c.match(l) {
+					continue
 				}
-
-				curNode = c
-				isParamMatch = true
-				if idx > 0 {
-					// ex. foo/bar/baz → /bar/baz
-					path = path[idx:]
+				*ps = append(*ps, Param{
+					key:   c.name,
+					value: l,
+				})
+				if m := c.search(path[len(l):], ps, opts); m != nil {
+					return m
 				}
+				// backtrack
+				*ps = (*ps)[:len(*ps)-1]
 			}
 		}
+	}
+
+	if n.catchAll != nil && n.catchAll.hasHandler() {
+		// ex. foo/bar/baz → foo/bar/baz
+		*ps = append(*ps, Param{
+			key:   n.catchAll.name,
+			value: path,
+		})
+		return n.catchAll
+	}
+
+	return nil
+}
+
+// searchStatic searches a node which has a handler for path below the static node n.
+// path is the rest of the request path including the label of n.
+func (n *node) searchStatic(path string, ps *Params, opts searchOption) *node {
+	fold := opts&searchFold != 0
+	if hasPrefix(path, n.label, fold) {
+		return n.search(path[len(n.label):], ps, opts)
+	}
+	// A route with a trailing slash. ex. foo → foo/
+	if opts&searchTSR != 0 && len(n.label) == len(path)+1 && hasPrefix(n.label, path, fold) && n.label[len(path)] == '/' {
+		return n.matchEnd(ps, opts&searchSlash != 0)
+	}
+	return nil
+}
+
+// matchEnd returns the node which has a handler for the end of a path at n.
+// If catchAll is true, a catch-all parameter below n matches the empty rest of the path. ex. static/ → static/*filepath
+func (n *node) matchEnd(ps *Params, catchAll bool) *node {
+	if n.hasHandler() {
+		return n
+	}
+	if catchAll && n.catchAll != nil && n.catchAll.hasHandler() {
+		*ps = append(*ps, Param{
+			key:   n.catchAll.name,
+			value: "",
+		})
+		return n.catchAll
+	}
+	return nil
+}
 
-		if !isParamMatch {
-			// no matching path was found.
-			return nil, nil, ErrNotFound
+// hasPrefix reports whether s begins with prefix.
+// If fold is true, ASCII letters are compared case-insensitively.
+func hasPrefix(s, prefix string, fold bool) bool {
+	if !fold {
+		return strings.HasPrefix(s, prefix)
+	}
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if s[i] != prefix[i] && toggleCase(s[i]) != prefix[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	action := curNode.action
-	if action.handler == nil {
-		// no matching handler and middlewares was found.
-		return nil, nil, ErrNotFound
+// toggleCase returns the other case of an ASCII letter c, otherwise c.
+func toggleCase(c byte) byte {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return c - 'a' + 'A'
+	case 'A' <= c && c <= 'Z':
+		return c - 'A' + 'a'
+	default:
+		return c
 	}
-	if params == nil {
-		return action, nil, nil
+}
+
+// fillPattern builds a path by replacing parameters and a catch-all parameter in pattern with values of ps in order.
+// ex. /users/:id[^\d+$]/*path, [42 a/b] → /users/42/a/b
+func fillPattern(pattern string, ps Params) string {
+	segments := strings.Split(pattern, "/")
+	i := 0
+	for j, seg := range segments {
+		if getNodeKind(seg) != nodeKindStatic && i < len(ps) {
+			segments[j] = ps[i].value
+			i++
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// walk calls fn for n and all descendants of n.
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+	for _, c := range n.params {
+		c.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
 	}
-	return action, params, nil
 }
 
 // getPattern gets a pattern from a label.
@@ -285,10 +762,10 @@ func (t *tree) Search(path string) (*action, Params, error) {
 // :id        → (.+)
 func getPattern(label string) string {
 	leftI := strings.Index(label, leftPtnDelimiter)
-	rightI := strings.Index(label, rightPtnDelimiter)
+	rightI := strings.LastIndex(label, rightPtnDelimiter)
 
 	// if label doesn't have any pattern, return wild card pattern as default.
-	if leftI == -1 || rightI == -1 {
+	if leftI == -1 || rightI == -1 || rightI < leftI {
 		return ""
 	}
 
@@ -298,30 +775,25 @@ func getPattern(label string) string {
 // getParamName gets a parameter from a label.
 // ex.
 // :id[^\d+$] → id
+// :id<int>   → id
 // :id        → id
 func getParamName(label string) string {
 	leftI := strings.Index(label, paramDelimiter)
-	rightI := func(l string) int {
-		var n int
-
-		for i := 0; i < len(l); i++ {
-			n = i
-			if l[i:i+1] == leftPtnDelimiter {
-				n = i
-				break
-			}
-			if i == len(l)-1 {
-				n = i + 1
-				break
-			}
-		}
-
-		return n
-	}(label)
+	rightI := strings.IndexAny(label, leftPtnDelimiter+leftConstraintDelimiter)
+	if rightI == -1 {
+		rightI = len(label)
+	}
 
 	return label[leftI+1 : rightI]
 }
 
+// getCatchAllName gets a catch-all parameter name from a label.
+// ex.
+// *filepath → filepath
+func getCatchAllName(label string) string {
+	return label[len(catchAllDelimiter):]
+}
+
 // cleanPath returns the canonical path for p, eliminating . and .. elements.
 // This method borrowed from from net/http package.
 // see https://cs.opensource.google/go/go/+/master:src/net/http/server.go;l=2310;bpv=1;bpt=1
@@ -346,6 +818,25 @@ func cleanPath(p string) string {
 	return np
 }
 
+// canonicalPath returns the cleaned path of a route.
+// A trailing slash after a catch-all parameter is removed because the catch-all parameter matches it.
+// ex. /foo/*path/ → /foo/*path
+func canonicalPath(path string) string {
+	path = cleanPath(path)
+	if i := strings.LastIndex(removeTrailingSlash(path), "/"); path[i+1:i+2] == catchAllDelimiter {
+		path = removeTrailingSlash(path)
+	}
+	return path
+}
+
+// toggleTrailingSlash removes a trailing slash from path if path has it, otherwise adds it.
+func toggleTrailingSlash(path string) string {
+	if path[len(path)-1:] == "/" {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
 // removeTrailingSlash removes trailing slash from path.
 func removeTrailingSlash(path string) string {
 	if path[len(path)-1:] == "/" {
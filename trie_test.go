@@ -1,10 +1,10 @@
 package goblin
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
-	"regexp"
 	"testing"
 )
 
@@ -12,9 +12,8 @@ func TestNewTree(t *testing.T) {
 	actual := newTree()
 	expected := &tree{
 		node: &node{
-			label:    "/",
-			action:   &action{},
-			children: []*node{},
+			label:  "/",
+			action: &action{},
 		},
 	}
 
@@ -170,7 +169,7 @@ func TestSearchFailure(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			tree := newTree()
 			for _, i := range c.insertItems {
-				tree.Insert(i.path, i.handler, i.middlewares)
+				mustInsert(t, tree, i.path, i.handler, i.middlewares)
 			}
 			actualAction, actualParams, err := tree.Search(c.searchItem.path)
 			if actualAction != nil || actualParams != nil {
@@ -184,12 +183,262 @@ func TestSearchFailure(t *testing.T) {
 	}
 }
 
+func TestInsertFailure(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name        string
+		insertItems []insertItem
+		expected    error
+	}{
+		{
+			name: "duplicate route",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:id`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrDuplicateRoute,
+		},
+		{
+			name: "ambiguous params",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:name/bar`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrAmbiguousRoute,
+		},
+		{
+			name: "ambiguous params with the same pattern",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[^\d+$]`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:num[^\d+$]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrAmbiguousRoute,
+		},
+		{
+			name: "ambiguous catch-all",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/*path`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/*filepath`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrAmbiguousRoute,
+		},
+		{
+			name: "missing right delimiter",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[^\d+$`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "missing left delimiter",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "characters after pattern",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[^\d+$]bar`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "empty pattern",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "empty substring pattern",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[~]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "regexp compile error",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[[\d+]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "missing param name",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "missing catch-all name",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/*`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "catch-all in the middle of path",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/*path/bar`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "ambiguous params with the same constraint",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id<int>`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:num<int>`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrAmbiguousRoute,
+		},
+		{
+			name: "unknown constraint",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id<number>`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "missing closing constraint delimiter",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id<int`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "empty constraint",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id<>`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "constraint and pattern",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id<int>[^\d+$]`,
+					handler: fooHandler,
+				},
+			},
+			expected: ErrInvalidPattern,
+		},
+		{
+			name: "no conflicts",
+			insertItems: []insertItem{
+				{
+					path:    `/foo/:id[^\d+$]`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:name`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:name/*path`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/:id<int>`,
+					handler: fooHandler,
+				},
+				{
+					path:    `/foo/bar`,
+					handler: fooHandler,
+				},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tree := newTree()
+			var err error
+			for _, i := range c.insertItems {
+				err = tree.Insert(i.path, i.handler, i.middlewares)
+			}
+			if !errors.Is(err, c.expected) {
+				t.Fatalf("err: %v expected: %v\n", err, c.expected)
+			}
+		})
+	}
+}
+
 func TestSearchOnlyRoot(t *testing.T) {
 	tree := newTree()
 
 	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -241,8 +490,8 @@ func TestSearchWithoutRoot(t *testing.T) {
 	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	barHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/foo`, fooHandler, []middleware{first})
-	tree.Insert(`/bar`, barHandler, []middleware{first})
+	mustInsert(t, tree, `/foo`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/bar`, barHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -285,7 +534,7 @@ func TestSearchCommonPrefix(t *testing.T) {
 
 	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/foo`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/foo`, fooHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -360,10 +609,10 @@ func TestSearchTrailingSlash(t *testing.T) {
 	barHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	fooBarHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
-	tree.Insert(`/foo/`, fooHandler, []middleware{first})
-	tree.Insert(`/bar/`, barHandler, []middleware{first})
-	tree.Insert(`/foo/bar/`, fooBarHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/bar/`, barHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/bar/`, fooBarHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -472,10 +721,10 @@ func TestSearchStaticPath(t *testing.T) {
 	barHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	fooBarHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
-	tree.Insert(`/foo`, fooHandler, []middleware{first})
-	tree.Insert(`/bar`, barHandler, []middleware{first})
-	tree.Insert(`/foo/bar`, fooBarHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/foo`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/bar`, barHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/bar`, fooBarHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -559,10 +808,10 @@ func TestSearchPathWithParams(t *testing.T) {
 	fooIDNameHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	fooIDNameDateHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/:id`, idHandler, []middleware{first})
-	tree.Insert(`/foo/:id`, fooIDHandler, []middleware{first})
-	tree.Insert(`/foo/:id/:name`, fooIDNameHandler, []middleware{first})
-	tree.Insert(`/foo/:id/:name/:date`, fooIDNameDateHandler, []middleware{first})
+	mustInsert(t, tree, `/:id`, idHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/:id`, fooIDHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/:id/:name`, fooIDNameHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/:id/:name/:date`, fooIDNameDateHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -656,12 +905,22 @@ func TestSearchPriority(t *testing.T) {
 	IDHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	IDPriorityHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
-	tree.Insert(`/`, rootPriorityHandler, []middleware{first})
-	tree.Insert(`/foo`, fooHandler, []middleware{first})
-	tree.Insert(`/foo`, fooPriorityHandler, []middleware{first})
-	tree.Insert(`/:id`, IDHandler, []middleware{first})
-	tree.Insert(`/:id`, IDPriorityHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/foo`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/:id`, IDHandler, []middleware{first})
+	// The routes inserted later overwrite the routes of the same paths.
+	for _, p := range []struct {
+		path    string
+		handler http.Handler
+	}{
+		{path: `/`, handler: rootPriorityHandler},
+		{path: `/foo`, handler: fooPriorityHandler},
+		{path: `/:id`, handler: IDPriorityHandler},
+	} {
+		if err := tree.Insert(p.path, p.handler, []middleware{first}); !errors.Is(err, ErrDuplicateRoute) {
+			t.Fatalf("actual: %v expected: %v\n", err, ErrDuplicateRoute)
+		}
+	}
 
 	cases := []caseWithFailure{
 		{
@@ -720,15 +979,17 @@ func TestSearchRegexp(t *testing.T) {
 	fooBarIDNameHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	bazInvalidIDHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
-	tree.Insert(`/:*[(.+)]`, rootWildCardHandler, []middleware{first})
-	tree.Insert(`/foo`, fooHandler, []middleware{first})
-	tree.Insert(`/foo/:id[^\d+$]`, fooIDHandler, []middleware{first})
-	tree.Insert(`/foo/:id[^\d+$]/:name[^\D+$]`, fooIDNameHandler, []middleware{first})
-	tree.Insert(`/foo/bar`, fooBarHandler, []middleware{first})
-	tree.Insert(`/foo/bar/:id`, fooBarIDHandler, []middleware{first})
-	tree.Insert(`/foo/bar/:id/:name`, fooBarIDNameHandler, []middleware{first})
-	tree.Insert(`/baz/:id[[\d+]`, bazInvalidIDHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/:*[(.+)]`, rootWildCardHandler, []middleware{first})
+	mustInsert(t, tree, `/foo`, fooHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/:id[^\d+$]`, fooIDHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/:id[^\d+$]/:name[^\D+$]`, fooIDNameHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/bar`, fooBarHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/bar/:id`, fooBarIDHandler, []middleware{first})
+	mustInsert(t, tree, `/foo/bar/:id/:name`, fooBarIDNameHandler, []middleware{first})
+	if err := tree.Insert(`/baz/:id[[\d+]`, bazInvalidIDHandler, []middleware{first}); !errors.Is(err, ErrInvalidPattern) {
+		t.Fatalf("actual: %v expected: %v\n", err, ErrInvalidPattern)
+	}
 
 	cases := []caseWithFailure{
 		{
@@ -919,8 +1180,8 @@ func TestSearchWildCardRegexp(t *testing.T) {
 	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	rootWildCardHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	tree.Insert(`/`, rootHandler, []middleware{first})
-	tree.Insert(`/:*[(.+)]`, rootWildCardHandler, []middleware{first})
+	mustInsert(t, tree, `/`, rootHandler, []middleware{first})
+	mustInsert(t, tree, `/:*[(.+)]`, rootWildCardHandler, []middleware{first})
 
 	cases := []caseWithFailure{
 		{
@@ -979,137 +1240,722 @@ func TestSearchWildCardRegexp(t *testing.T) {
 	testWithFailure(t, tree, cases)
 }
 
-func testWithFailure(t *testing.T, tree *tree, cases []caseWithFailure) {
-	t.Helper()
-	for _, c := range cases {
-		t.Run(c.name(), func(t *testing.T) {
-			actualAction, actualParams, err := tree.Search(c.item.path)
-
-			if c.hasError {
-				if err == nil {
-					t.Fatalf("actualAction: %v actualParams: %v expected err: %v", actualAction, actualParams, err)
-				}
-
-				if actualAction != c.expectedAction {
-					t.Errorf("actualAction:%v expectedAction:%v", actualAction, c.expectedAction)
-				}
-
-				if len(actualParams) != len(c.expectedParams) {
-					t.Errorf("actualParams: %v expectedParams: %v\n", len(actualParams), len(c.expectedParams))
-				}
-
-				for i, Param := range actualParams {
-					if !reflect.DeepEqual(Param, c.expectedParams[i]) {
-						t.Errorf("actualParams: %v expectedParams: %v\n", Param, c.expectedParams[i])
-					}
-				}
-			} else {
-				if err != nil {
-					t.Fatalf("actualAction: %v actualParams: %v expected err: %v", actualAction, actualParams, err)
-				}
-
-				if reflect.ValueOf(actualAction.handler) != reflect.ValueOf(c.expectedAction.handler) {
-					t.Errorf("actualActionHandler:%v expectedActionHandler:%v", actualAction.handler, c.expectedAction.handler)
-				}
-
-				if len(actualAction.middlewares) != len(c.expectedAction.middlewares) {
-					t.Errorf("actualActionMiddlewares: %v expectedActionsMiddleware: %v\n", len(actualAction.middlewares), len(c.expectedAction.middlewares))
-				}
-
-				for i, mws := range actualAction.middlewares {
-					if reflect.ValueOf(mws) != reflect.ValueOf(c.expectedAction.middlewares[i]) {
-						t.Errorf("actualActionsMiddleware: %v expectedActionsMiddleware: %v\n", mws, c.expectedAction.middlewares[i])
-					}
-				}
-
-				if len(actualParams) != len(c.expectedParams) {
-					t.Errorf("actualParams: %v expectedParams: %v\n", len(actualParams), len(c.expectedParams))
-				}
-
-				for i, Param := range actualParams {
-					if !reflect.DeepEqual(Param, c.expectedParams[i]) {
-						t.Errorf("actualParam: %v expectedParam: %v\n", Param, c.expectedParams[i])
-					}
-				}
-			}
-		})
-	}
-}
+func TestSearchRegexpAnchoring(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-func TestGetReg(t *testing.T) {
 	cases := []struct {
-		name        string
-		ptn         string
-		isCached    bool
-		expectedReg *regexp.Regexp
-		expectedErr error
+		name      string
+		path      string
+		reqPath   string
+		expected  bool
+		substring bool
 	}{
-		{
-			name:        "Valid - no cache",
-			ptn:         `\d+`,
-			isCached:    false,
-			expectedReg: regexp.MustCompile(`\d+`),
-			expectedErr: nil,
-		},
-		{
-			name:        "Valid - cached",
-			ptn:         `\d+`,
-			isCached:    true,
-			expectedReg: regexp.MustCompile(`\d+`),
-			expectedErr: nil,
-		},
-		{
-			name:        "Invalid - regexp compile error",
-			ptn:         `[\d+`,
-			isCached:    false,
-			expectedReg: nil,
-			expectedErr: fmt.Errorf("error parsing regexp: missing closing ]: `[\\d+`"),
-		},
+		{name: "whole segment", path: `/foo/:id[\d+]`, reqPath: "/foo/123", expected: true},
+		{name: "part of segment", path: `/foo/:id[\d+]`, reqPath: "/foo/abc1def", expected: false},
+		{name: "alternation", path: `/foo/:id[a|b]`, reqPath: "/foo/ab", expected: false},
+		{name: "anchored", path: `/foo/:id[^\d+$]`, reqPath: "/foo/abc1def", expected: false},
+		{name: "opt-out", path: `/foo/:id[~\d+]`, reqPath: "/foo/abc1def", expected: true},
+		{name: "opt-out without match", path: `/foo/:id[~\d+]`, reqPath: "/foo/abc", expected: false},
+		{name: "substring - whole segment", path: `/foo/:id[\d+]`, reqPath: "/foo/123", expected: true, substring: true},
+		{name: "substring - part of segment", path: `/foo/:id[\d+]`, reqPath: "/foo/abc1def", expected: true, substring: true},
+		{name: "substring - alternation", path: `/foo/:id[a|b]`, reqPath: "/foo/ab", expected: true, substring: true},
+		{name: "substring - anchored", path: `/foo/:id[^\d+$]`, reqPath: "/foo/abc1def", expected: false, substring: true},
+		{name: "substring - opt-out", path: `/foo/:id[~\d+]`, reqPath: "/foo/abc1def", expected: true, substring: true},
 	}
 
-	cache := regCache{}
-
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			if c.isCached {
-				cache.s.Store(c.ptn, c.expectedReg)
-			}
-			reg, err := cache.getReg(c.ptn)
-
-			if !reflect.DeepEqual(reg, c.expectedReg) {
-				t.Errorf("actual:%v expected:%v", reg, c.expectedReg)
+			tree := newTree()
+			if err := tree.insert(c.path, &action{handler: handler}, nil, c.substring); err != nil {
+				t.Fatalf("actual: %v expected: %v\n", err, nil)
 			}
-
-			if err != nil {
-				if err.Error() != c.expectedErr.Error() {
-					t.Errorf("actual:%v expected:%v", err.Error(), c.expectedErr.Error())
-				}
+			_, _, err := tree.Search(c.reqPath)
+			if actual := err == nil; actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
 			}
 		})
 	}
 }
 
-func TestGetPattern(t *testing.T) {
-	cases := []struct {
-		name     string
-		actual   string
-		expected string
-	}{
+func TestSearchCatchAll(t *testing.T) {
+	tree := newTree()
+
+	staticHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	staticFaviconHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	staticCatchAllHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	filesIDHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	filesCatchAllHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mustInsert(t, tree, `/static`, staticHandler, []middleware{first})
+	mustInsert(t, tree, `/static/favicon.ico`, staticFaviconHandler, []middleware{first})
+	mustInsert(t, tree, `/static/*filepath`, staticCatchAllHandler, []middleware{first})
+	mustInsert(t, tree, `/files/:id`, filesIDHandler, []middleware{first})
+	mustInsert(t, tree, `/files/*filepath`, filesCatchAllHandler, []middleware{first})
+
+	cases := []caseWithFailure{
 		{
-			name:     "valid pattern",
-			actual:   getPattern(`:id[^\d+$]`),
-			expected: `^\d+$`,
+			hasError: false,
+			item: &item{
+				path: "/static",
+			},
+			expectedAction: &action{
+				handler:     staticHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{},
 		},
 		{
-			name:     "invalid pattern one",
-			actual:   getPattern(`:id[`),
-			expected: "",
+			hasError: false,
+			item: &item{
+				path: "/static/favicon.ico",
+			},
+			expectedAction: &action{
+				handler:     staticFaviconHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{},
 		},
 		{
-			name:     "invalid pattern two",
+			hasError: false,
+			item: &item{
+				path: "/static/app.js",
+			},
+			expectedAction: &action{
+				handler:     staticCatchAllHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "filepath",
+					value: "app.js",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/static/css/app.css",
+			},
+			expectedAction: &action{
+				handler:     staticCatchAllHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "filepath",
+					value: "css/app.css",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/files/1",
+			},
+			expectedAction: &action{
+				handler:     filesIDHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "id",
+					value: "1",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/files/a/b/c",
+			},
+			expectedAction: &action{
+				handler:     filesCatchAllHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "filepath",
+					value: "a/b/c",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/files/",
+			},
+			expectedAction: &action{
+				handler:     filesCatchAllHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "filepath",
+					value: "",
+				},
+			},
+		},
+		{
+			hasError: true,
+			item: &item{
+				path: "/files",
+			},
+			expectedAction: nil,
+			expectedParams: Params{},
+		},
+	}
+
+	testWithFailure(t, tree, cases)
+}
+
+func TestSearchBacktracking(t *testing.T) {
+	tree := newTree()
+
+	usersMeHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersIDHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersNameHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersIDProfileHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersNameSettingsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersCatchAllHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// Insert in the reverse order of priority to make sure that the order of insertion doesn't matter.
+	mustInsert(t, tree, `/users/*path`, usersCatchAllHandler, []middleware{first})
+	mustInsert(t, tree, `/users/:name`, usersNameHandler, []middleware{first})
+	mustInsert(t, tree, `/users/:name/settings`, usersNameSettingsHandler, []middleware{first})
+	mustInsert(t, tree, `/users/:id[^\d+$]`, usersIDHandler, []middleware{first})
+	mustInsert(t, tree, `/users/:id[^\d+$]/profile`, usersIDProfileHandler, []middleware{first})
+	mustInsert(t, tree, `/users/me`, usersMeHandler, []middleware{first})
+
+	cases := []caseWithFailure{
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/me",
+			},
+			expectedAction: &action{
+				handler:     usersMeHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/1",
+			},
+			expectedAction: &action{
+				handler:     usersIDHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "id",
+					value: "1",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/john",
+			},
+			expectedAction: &action{
+				handler:     usersNameHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "name",
+					value: "john",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/1/profile",
+			},
+			expectedAction: &action{
+				handler:     usersIDProfileHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "id",
+					value: "1",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/me/settings",
+			},
+			expectedAction: &action{
+				handler:     usersNameSettingsHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "name",
+					value: "me",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/1/settings",
+			},
+			expectedAction: &action{
+				handler:     usersNameSettingsHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "name",
+					value: "1",
+				},
+			},
+		},
+		{
+			hasError: false,
+			item: &item{
+				path: "/users/me/profile",
+			},
+			expectedAction: &action{
+				handler:     usersCatchAllHandler,
+				middlewares: []middleware{first},
+			},
+			expectedParams: Params{
+				{
+					key:   "path",
+					value: "me/profile",
+				},
+			},
+		},
+		{
+			hasError: true,
+			item: &item{
+				path: "/users",
+			},
+			expectedAction: nil,
+			expectedParams: Params{},
+		},
+	}
+
+	testWithFailure(t, tree, cases)
+}
+
+func TestSearchStrictSlash(t *testing.T) {
+	tree := newTree()
+
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	barHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mustInsert(t, tree, `/foo`, fooHandler, nil)
+	mustInsert(t, tree, `/bar/`, barHandler, nil)
+
+	cases := []struct {
+		path     string
+		tsr      bool
+		expected http.Handler
+	}{
+		{path: "/foo", tsr: true, expected: fooHandler},
+		{path: "/bar", tsr: true, expected: barHandler},
+		{path: "/bar/", tsr: true, expected: barHandler},
+		{path: "/foo", tsr: false, expected: fooHandler},
+		{path: "/foo/", tsr: false, expected: nil},
+		{path: "/bar", tsr: false, expected: nil},
+		{path: "/bar/", tsr: false, expected: barHandler},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%t", c.path, c.tsr), func(t *testing.T) {
+			var opts searchOption
+			if c.tsr {
+				opts = searchTSR
+			}
+			actual, _, err := tree.search(c.path, opts)
+			if c.expected == nil {
+				if err != ErrNotFound {
+					t.Errorf("actual: %v expected: %v\n", err, ErrNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("actual: %v expected: %v\n", err, nil)
+			}
+			if reflect.ValueOf(actual.handler) != reflect.ValueOf(c.expected) {
+				t.Errorf("actual: %v expected: %v\n", actual.handler, c.expected)
+			}
+		})
+	}
+}
+
+func TestSearchFold(t *testing.T) {
+	tree := newTree()
+
+	usersIDHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	usersMeHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	upperUsersMeHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mustInsert(t, tree, `/users/:id`, usersIDHandler, nil)
+	mustInsert(t, tree, `/users/me`, usersMeHandler, nil)
+	mustInsert(t, tree, `/Users/Me`, upperUsersMeHandler, nil)
+
+	cases := []struct {
+		path           string
+		opts           searchOption
+		expected       http.Handler
+		expectedParams Params
+	}{
+		{path: "/users/me", opts: searchFold, expected: usersMeHandler},
+		{path: "/Users/Me", opts: searchFold, expected: upperUsersMeHandler},
+		{path: "/uSERS/ME", opts: searchFold, expected: usersMeHandler},
+		{path: "/USERS/AbC", opts: searchFold, expected: usersIDHandler, expectedParams: Params{{key: "id", value: "AbC"}}},
+		{path: "/USERS/AbC", opts: 0, expected: nil},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%d", c.path, c.opts), func(t *testing.T) {
+			actual, params, err := tree.search(c.path, c.opts)
+			if c.expected == nil {
+				if err != ErrNotFound {
+					t.Errorf("actual: %v expected: %v\n", err, ErrNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("actual: %v expected: %v\n", err, nil)
+			}
+			if reflect.ValueOf(actual.handler) != reflect.ValueOf(c.expected) {
+				t.Errorf("actual: %v expected: %v\n", actual.handler, c.expected)
+			}
+			if !reflect.DeepEqual(params, c.expectedParams) {
+				t.Errorf("actual: %v expected: %v\n", params, c.expectedParams)
+			}
+		})
+	}
+}
+
+func TestTreeClone(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	barHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	original := newTree()
+	mustInsert(t, original, `/foo`, fooHandler, nil)
+	mustInsert(t, original, `/foo/:id`, fooHandler, nil)
+
+	clone := original.clone()
+	mustInsert(t, clone, `/fo`, barHandler, nil)
+	if err := clone.Insert(`/foo/:id`, barHandler, nil); !errors.Is(err, ErrDuplicateRoute) {
+		t.Fatalf("actual: %v expected: %v\n", err, ErrDuplicateRoute)
+	}
+	mustInsert(t, clone, `/foo/:id/*path`, barHandler, nil)
+	clone.Remove(`/foo`)
+
+	cases := []struct {
+		tree     *tree
+		path     string
+		expected http.Handler
+	}{
+		{tree: original, path: "/foo", expected: fooHandler},
+		{tree: original, path: "/foo/1", expected: fooHandler},
+		{tree: original, path: "/fo", expected: nil},
+		{tree: original, path: "/foo/1/bar", expected: nil},
+		{tree: clone, path: "/foo", expected: nil},
+		{tree: clone, path: "/foo/1", expected: barHandler},
+		{tree: clone, path: "/fo", expected: barHandler},
+		{tree: clone, path: "/foo/1/bar", expected: barHandler},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, c.path), func(t *testing.T) {
+			actual, _, err := c.tree.Search(c.path)
+			if c.expected == nil {
+				if err != ErrNotFound {
+					t.Errorf("actual: %v expected: %v\n", err, ErrNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("actual: %v expected: %v\n", err, nil)
+			}
+			if reflect.ValueOf(actual.handler) != reflect.ValueOf(c.expected) {
+				t.Errorf("actual: %v expected: %v\n", actual.handler, c.expected)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tree := newTree()
+	mustInsert(t, tree, `/foo`, fooHandler, nil)
+	mustInsert(t, tree, `/foo/bar`, fooHandler, nil)
+	mustInsert(t, tree, `/foo/:id`, fooHandler, nil)
+	mustInsert(t, tree, `/foo/:id[^\d+$]/baz`, fooHandler, nil)
+	mustInsert(t, tree, `/files/*path`, fooHandler, nil)
+
+	cases := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/foo/:name", expected: false},
+		{path: `/foo/:id[^\d+$]`, expected: false},
+		{path: "/fo", expected: false},
+		{path: "/foo/:id", expected: true},
+		{path: "/foo/:id", expected: false},
+		{path: `/foo/:id[^\d+$]/baz`, expected: true},
+		{path: "/foo/bar", expected: true},
+		{path: "/files/*path/", expected: true},
+		{path: `/foo/:id[^\d+$`, expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			if actual := tree.Remove(c.path); actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+
+	// The nodes which no longer lead to any routes are removed.
+	foo := tree.node.getStaticChild('f').getStaticChild('o')
+	if foo == nil || foo.label != "oo" || len(foo.children) != 0 || len(foo.params) != 0 || foo.catchAll != nil {
+		t.Errorf("actual: %v expected: %v\n", foo, "oo without children")
+	}
+	if _, _, err := tree.Search("/foo"); err != nil {
+		t.Errorf("actual: %v expected: %v\n", err, nil)
+	}
+	// A removed param can be registered with another name without conflicts.
+	if err := tree.Insert(`/foo/:name`, fooHandler, nil); err != nil {
+		t.Errorf("actual: %v expected: %v\n", err, nil)
+	}
+}
+
+func TestInsertParam(t *testing.T) {
+	n := &node{
+		label: "/",
+	}
+	labels := []string{`:name`, `:id[^\d+$]`, `:date[^\d{8}$]`, `:name`}
+	tree := newTree()
+	for _, l := range labels {
+		tree.insertParam(n, l, nil)
+	}
+
+	expected := []string{`:id[^\d+$]`, `:date[^\d{8}$]`, `:name`}
+	actual := []string{}
+	for _, c := range n.params {
+		actual = append(actual, c.label)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual:%v expected:%v", actual, expected)
+	}
+}
+
+func TestInsertStatic(t *testing.T) {
+	n := &node{
+		label: "/",
+	}
+	tree := newTree()
+	foo := tree.insertStatic(n, "foo")
+	foobar := tree.insertStatic(n, "foo/bar")
+	fo := tree.insertStatic(n, "fo")
+	baz := tree.insertStatic(n, "baz")
+
+	if len(n.children) != 2 || n.indices != "fb" {
+		t.Fatalf("actual:%v expected:%v", n.indices, "fb")
+	}
+	if fo != n.children[0] || fo.label != "fo" {
+		t.Errorf("actual:%v expected:%v", fo.label, "fo")
+	}
+	if foo != fo.children[0] || foo.label != "o" {
+		t.Errorf("actual:%v expected:%v", foo.label, "o")
+	}
+	if foobar != foo.children[0] || foobar.label != "/bar" {
+		t.Errorf("actual:%v expected:%v", foobar.label, "/bar")
+	}
+	if baz != n.children[1] || baz.label != "baz" {
+		t.Errorf("actual:%v expected:%v", baz.label, "baz")
+	}
+}
+
+func TestIndexParam(t *testing.T) {
+	cases := []struct {
+		name     string
+		actual   int
+		expected int
+	}{
+		{
+			name:     "param at head",
+			actual:   indexParam(`:id/foo`),
+			expected: 0,
+		},
+		{
+			name:     "param",
+			actual:   indexParam(`foo/:id`),
+			expected: 4,
+		},
+		{
+			name:     "catch-all",
+			actual:   indexParam(`foo/*path`),
+			expected: 4,
+		},
+		{
+			name:     "delimiter in the middle of a segment",
+			actual:   indexParam(`foo:bar/baz*`),
+			expected: -1,
+		},
+		{
+			name:     "no params",
+			actual:   indexParam(`foo/bar`),
+			expected: -1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.actual != c.expected {
+				t.Errorf("actual:%v expected:%v", c.actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestGetNodeKind(t *testing.T) {
+	cases := []struct {
+		name     string
+		actual   nodeKind
+		expected nodeKind
+	}{
+		{
+			name:     "static",
+			actual:   getNodeKind(`foo`),
+			expected: nodeKindStatic,
+		},
+		{
+			name:     "regexp",
+			actual:   getNodeKind(`:id[^\d+$]`),
+			expected: nodeKindRegexp,
+		},
+		{
+			name:     "constraint",
+			actual:   getNodeKind(`:id<int>`),
+			expected: nodeKindRegexp,
+		},
+		{
+			name:     "param",
+			actual:   getNodeKind(`:id`),
+			expected: nodeKindParam,
+		},
+		{
+			name:     "catch-all",
+			actual:   getNodeKind(`*filepath`),
+			expected: nodeKindCatchAll,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.actual != c.expected {
+				t.Errorf("actual:%v expected:%v", c.actual, c.expected)
+			}
+		})
+	}
+}
+
+func testWithFailure(t *testing.T, tree *tree, cases []caseWithFailure) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name(), func(t *testing.T) {
+			actualAction, actualParams, err := tree.Search(c.item.path)
+
+			if c.hasError {
+				if err == nil {
+					t.Fatalf("actualAction: %v actualParams: %v expected err: %v", actualAction, actualParams, err)
+				}
+
+				if actualAction != c.expectedAction {
+					t.Errorf("actualAction:%v expectedAction:%v", actualAction, c.expectedAction)
+				}
+
+				if len(actualParams) != len(c.expectedParams) {
+					t.Errorf("actualParams: %v expectedParams: %v\n", len(actualParams), len(c.expectedParams))
+				}
+
+				for i, Param := range actualParams {
+					if !reflect.DeepEqual(Param, c.expectedParams[i]) {
+						t.Errorf("actualParams: %v expectedParams: %v\n", Param, c.expectedParams[i])
+					}
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("actualAction: %v actualParams: %v expected err: %v", actualAction, actualParams, err)
+				}
+
+				if reflect.ValueOf(actualAction.handler) != reflect.ValueOf(c.expectedAction.handler) {
+					t.Errorf("actualActionHandler:%v expectedActionHandler:%v", actualAction.handler, c.expectedAction.handler)
+				}
+
+				if len(actualAction.middlewares) != len(c.expectedAction.middlewares) {
+					t.Errorf("actualActionMiddlewares: %v expectedActionsMiddleware: %v\n", len(actualAction.middlewares), len(c.expectedAction.middlewares))
+				}
+
+				for i, mws := range actualAction.middlewares {
+					if reflect.ValueOf(mws) != reflect.ValueOf(c.expectedAction.middlewares[i]) {
+						t.Errorf("actualActionsMiddleware: %v expectedActionsMiddleware: %v\n", mws, c.expectedAction.middlewares[i])
+					}
+				}
+
+				if len(actualParams) != len(c.expectedParams) {
+					t.Errorf("actualParams: %v expectedParams: %v\n", len(actualParams), len(c.expectedParams))
+				}
+
+				for i, Param := range actualParams {
+					if !reflect.DeepEqual(Param, c.expectedParams[i]) {
+						t.Errorf("actualParam: %v expectedParam: %v\n", Param, c.expectedParams[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetPattern(t *testing.T) {
+	cases := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{
+			name:     "valid pattern",
+			actual:   getPattern(`:id[^\d+$]`),
+			expected: `^\d+$`,
+		},
+		{
+			name:     "invalid pattern one",
+			actual:   getPattern(`:id[`),
+			expected: "",
+		},
+		{
+			name:     "invalid pattern two",
 			actual:   getPattern(`:id]`),
 			expected: "",
 		},
+		{
+			name:     "invalid pattern three",
+			actual:   getPattern(`:id][`),
+			expected: "",
+		},
+		{
+			name:     "pattern with brackets",
+			actual:   getPattern(`:id[[a-z]+]`),
+			expected: `[a-z]+`,
+		},
 		{
 			name:     "missing pattern",
 			actual:   getPattern(`:id`),
@@ -1147,6 +1993,11 @@ func TestGetParamName(t *testing.T) {
 			actual:   getParamName(`:id]`),
 			expected: "id]",
 		},
+		{
+			name:     "constraint",
+			actual:   getParamName(`:id<int>`),
+			expected: "id",
+		},
 		{
 			name:     "missing pattern",
 			actual:   getParamName(`:id`),
@@ -1163,6 +2014,33 @@ func TestGetParamName(t *testing.T) {
 	}
 }
 
+func TestGetCatchAllName(t *testing.T) {
+	cases := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{
+			name:     "valid catch-all",
+			actual:   getCatchAllName(`*filepath`),
+			expected: "filepath",
+		},
+		{
+			name:     "missing name",
+			actual:   getCatchAllName(`*`),
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.actual != c.expected {
+				t.Errorf("actual:%v expected:%v", c.actual, c.expected)
+			}
+		})
+	}
+}
+
 func TestCleanPath(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -1263,3 +2141,77 @@ func TestRemoveTrailingSlash(t *testing.T) {
 		})
 	}
 }
+
+func TestToggleTrailingSlash(t *testing.T) {
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/foo", expected: "/foo/"},
+		{path: "/foo/", expected: "/foo"},
+		{path: "/foo/:id", expected: "/foo/:id/"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			actual := toggleTrailingSlash(c.path)
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	cases := []struct {
+		s        string
+		prefix   string
+		fold     bool
+		expected bool
+	}{
+		{s: "users/42", prefix: "users/", fold: false, expected: true},
+		{s: "Users/42", prefix: "users/", fold: false, expected: false},
+		{s: "Users/42", prefix: "users/", fold: true, expected: true},
+		{s: "USERS", prefix: "users/", fold: true, expected: false},
+		{s: "users@", prefix: "users`", fold: true, expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s_%s_%t", c.s, c.prefix, c.fold), func(t *testing.T) {
+			actual := hasPrefix(c.s, c.prefix, c.fold)
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestFillPattern(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		params   Params
+		expected string
+	}{
+		{pattern: "/users/me", params: nil, expected: "/users/me"},
+		{pattern: "/users/:id", params: Params{{key: "id", value: "AbC"}}, expected: "/users/AbC"},
+		{pattern: `/users/:id[^\d+$]/posts/:name/`, params: Params{{key: "id", value: "42"}, {key: "name", value: "Foo"}}, expected: "/users/42/posts/Foo/"},
+		{pattern: "/files/*path", params: Params{{key: "path", value: "A/b"}}, expected: "/files/A/b"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			actual := fillPattern(c.pattern, c.params)
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+// mustInsert inserts a route to tree, and fails the test if Insert returns an error.
+func mustInsert(t *testing.T, tree *tree, path string, handler http.Handler, mws middlewares) {
+	t.Helper()
+	if err := tree.Insert(path, handler, mws); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+}
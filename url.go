@@ -0,0 +1,105 @@
+package goblin
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// namedRoute is a route which has a name.
+type namedRoute struct {
+	pattern  string              // cleaned path of the route
+	matchers []func(string) bool // compiled patterns and constraints of the parameters of the route
+}
+
+// nameRoute registers name for the route of path.
+// A route which is invalid isn't registered, so that name isn't registered either.
+func (r *Router) nameRoute(name string, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path = cleanPath(path)
+	matchers, err := compileParams(path, r.loadTable().constraints, r.settings().SubstringPatterns)
+	if err != nil {
+		return
+	}
+	if nr, ok := r.names[name]; ok && nr.pattern != path {
+		r.reportErr(fmt.Errorf("goblin: name %s: %w: %s already has the name", name, ErrDuplicateRoute, nr.pattern))
+	}
+	if r.names == nil {
+		r.names = map[string]namedRoute{}
+	}
+	r.names[name] = namedRoute{
+		pattern:  path,
+		matchers: matchers,
+	}
+}
+
+// URL builds the path of the route which has name, replacing parameters with the given values.
+// pairs are pairs of a parameter name and a value. ex. URL("user", "id", "42") → /users/42
+// A value must satisfy the pattern of the parameter, and it is escaped.
+// A value of a catch-all parameter can have slashes. ex. URL("file", "path", "css/main.css") → /files/css/main.css
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	r.mu.Lock()
+	nr, ok := r.names[name]
+	r.mu.Unlock()
+	pattern := nr.pattern
+	if !ok {
+		return "", fmt.Errorf("goblin: %s: %w", name, ErrUnknownRoute)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("goblin: %s: %w: %s doesn't have a value", name, ErrMissingParam, pairs[len(pairs)-1])
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	names := map[string]bool{}
+	segments := strings.Split(pattern, "/")
+	j := -1
+	for i, seg := range segments {
+		kind := getNodeKind(seg)
+		if kind == nodeKindStatic {
+			continue
+		}
+
+		var pn string
+		if kind == nodeKindCatchAll {
+			pn = getCatchAllName(seg)
+		} else {
+			pn = getParamName(seg)
+		}
+		names[pn] = true
+		j++
+		v, ok := values[pn]
+		if !ok {
+			return "", fmt.Errorf("goblin: %s: %w: %s", name, ErrMissingParam, pn)
+		}
+
+		switch kind {
+		case nodeKindCatchAll:
+			parts := strings.Split(v, "/")
+			for j, p := range parts {
+				parts[j] = url.PathEscape(p)
+			}
+			segments[i] = strings.Join(parts, "/")
+			continue
+		case nodeKindRegexp:
+			if j < len(nr.matchers) && nr.matchers[j] != nil && !nr.matchers[j](v) {
+				return "", fmt.Errorf("goblin: %s: %w: %s=%q doesn't match %s", name, ErrInvalidParam, pn, v, getParamConstraint(seg))
+			}
+		}
+		if v == "" || strings.Contains(v, "/") {
+			return "", fmt.Errorf("goblin: %s: %w: %s=%q must be a non-empty segment", name, ErrInvalidParam, pn, v)
+		}
+		segments[i] = url.PathEscape(v)
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		if !names[pairs[i]] {
+			return "", fmt.Errorf("goblin: %s: %w: %s isn't a parameter of %s", name, ErrInvalidParam, pairs[i], pattern)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
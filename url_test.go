@@ -0,0 +1,76 @@
+package goblin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet).Name("root").Handler(`/`, fooHandler)
+	r.Methods(http.MethodGet).Name("user").Handler(`/users/:id[^\d+$]`, fooHandler)
+	r.Methods(http.MethodGet).Name("post").Handler(`/users/:id[^\d+$]/posts/:slug`, fooHandler)
+	r.Methods(http.MethodGet).Name("file").Handler(`/files/*path`, fooHandler)
+	r.Methods(http.MethodGet).Name("item").Handler(`/items/{id:[0-9]+}`, fooHandler)
+	r.Methods(http.MethodGet).Name("order").Handler(`/orders/:id<uint>`, fooHandler)
+	r.Group(`/api`, func(g *Group) {
+		g.Methods(http.MethodGet).Name("api.user").Handler(`/users/:name`, fooHandler)
+	})
+
+	cases := []struct {
+		name     string
+		pairs    []string
+		expected string
+		err      error
+	}{
+		{name: "root", expected: "/"},
+		{name: "user", pairs: []string{"id", "42"}, expected: "/users/42"},
+		{name: "post", pairs: []string{"slug", "hello world", "id", "42"}, expected: "/users/42/posts/hello%20world"},
+		{name: "file", pairs: []string{"path", "css/main 1.css"}, expected: "/files/css/main%201.css"},
+		{name: "file", pairs: []string{"path", ""}, expected: "/files/"},
+		{name: "api.user", pairs: []string{"name", "john"}, expected: "/api/users/john"},
+		{name: "item", pairs: []string{"id", "42"}, expected: "/items/42"},
+		{name: "item", pairs: []string{"id", "a42"}, err: ErrInvalidParam},
+		{name: "order", pairs: []string{"id", "42"}, expected: "/orders/42"},
+		{name: "order", pairs: []string{"id", "-42"}, err: ErrInvalidParam},
+		{name: "unknown", err: ErrUnknownRoute},
+		{name: "user", err: ErrMissingParam},
+		{name: "user", pairs: []string{"id"}, err: ErrMissingParam},
+		{name: "user", pairs: []string{"id", "john"}, err: ErrInvalidParam},
+		{name: "user", pairs: []string{"id", "42", "name", "john"}, err: ErrInvalidParam},
+		{name: "api.user", pairs: []string{"name", ""}, err: ErrInvalidParam},
+		{name: "api.user", pairs: []string{"name", "a/b"}, err: ErrInvalidParam},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name+"_"+strings.Join(c.pairs, "_"), func(t *testing.T) {
+			actual, err := r.URL(c.name, c.pairs...)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("actual: %v expected: %v\n", err, c.err)
+			}
+			if actual != c.expected {
+				t.Errorf("actual: %v expected: %v\n", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestNameDuplicate(t *testing.T) {
+	fooHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	r := NewRouter()
+	r.Methods(http.MethodGet).Name("user").Handler(`/users/:id`, fooHandler)
+	r.Methods(http.MethodPost).Name("user").Handler(`/users/:id`, fooHandler)
+	if err := r.Validate(); err != nil {
+		t.Fatalf("actual: %v expected: %v\n", err, nil)
+	}
+
+	r.Methods(http.MethodGet).Name("user").Handler(`/members/:id`, fooHandler)
+	if err := r.Validate(); !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("actual: %v expected: %v\n", err, ErrDuplicateRoute)
+	}
+}